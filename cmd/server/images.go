@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"gin-starter/internal/config"
+	"gin-starter/internal/service/image"
+)
+
+// newImageProcessor создает кэш и сервис обработки изображений по конфигу.
+// Отмена ctx прерывает идущую обработку. Возвращаемая функция останавливает кэш
+// и закрывает каталог медиа
+func newImageProcessor(ctx context.Context, cfg *config.Config, overlays *image.Overlays) (*image.ProcessorService, func()) {
+	imageCache, err := image.NewCache(image.CacheOptions{
+		TTL:         cfg.ImageCacheTTL,
+		MemoryBytes: cfg.ImageCacheMemoryBytes,
+		DiskDir:     cfg.ImageCacheDiskDir,
+		DiskBytes:   cfg.ImageCacheDiskBytes,
+	})
+	if err != nil {
+		log.Printf("⚠️ Warning: %v, using memory-only image cache", err)
+	}
+
+	// Исходники изображений читаются только через os.Root: он не выпускает
+	// за пределы каталога ни через "..", ни через симлинки
+	mediaRoot, err := os.OpenRoot(cfg.ImageMediaDir)
+	if err != nil {
+		log.Fatalf("failed to open media dir %s: %v", cfg.ImageMediaDir, err)
+	}
+
+	processor := image.NewProcessorService(image.ProcessorOptions{
+		Media:                  mediaRoot.FS(),
+		AllowedExtensions:      cfg.ImageAllowedExtensions,
+		MaxMegapixels:          cfg.ImageMaxMegapixels,
+		MaxFrames:              cfg.ImageMaxFrames,
+		MaxAnimationMegapixels: cfg.ImageMaxAnimationMP,
+		Cache:                  imageCache,
+		MaxConcurrent:          cfg.ImageMaxConcurrent,
+		QueueTimeout:           cfg.ImageQueueTimeout,
+		ProcessTimeout:         cfg.ImageProcessTimeout,
+		MemoryBudget:           cfg.ImageMemoryBudget,
+		Remote: image.RemoteOptions{
+			AllowedHosts: cfg.ImageRemoteAllowedHosts,
+			MaxBytes:     cfg.ImageRemoteMaxBytes,
+			Timeout:      cfg.ImageRemoteTimeout,
+			TTL:          cfg.ImageRemoteTTL,
+		},
+		Overlays:    overlays,
+		BaseContext: ctx,
+	})
+
+	return processor, func() {
+		if err := image.CloseCache(imageCache); err != nil {
+			log.Printf("⚠️ Warning: failed to close image cache: %v", err)
+		}
+		_ = mediaRoot.Close()
+	}
+}
+
+// runWarmup прогревает кэш изображений по настройкам из конфига: варианты
+// для адаптивных картинок и все пресеты
+func runWarmup(ctx context.Context, cfg *config.Config, processor *image.ProcessorService, presets *image.Presets) error {
+	if cfg.ImageCacheDiskDir == "" {
+		log.Println("⚠️ Warning: disk image cache is disabled, warmed variants live only in memory")
+	}
+
+	formats := make([]image.Format, 0, len(cfg.ImageWarmupFormats))
+	for _, value := range cfg.ImageWarmupFormats {
+		format, err := image.ParseFormat(value)
+		if err != nil {
+			log.Printf("⚠️ Warning: image warm-up: %v", err)
+			continue
+		}
+		formats = append(formats, format)
+	}
+
+	log.Println("🔥 Image warm-up started")
+	stats, err := processor.Warmup(ctx, image.WarmupOptions{
+		Manifest:    cfg.ImageWarmupManifest,
+		Dir:         cfg.ImageWarmupDir,
+		Widths:      cfg.ImageWarmupWidths,
+		Formats:     formats,
+		Presets:     presets.List(),
+		Quality:     cfg.ImageWarmupQuality,
+		Concurrency: cfg.ImageWarmupConcurrency,
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("🔥 Image warm-up finished: %s", stats)
+	return nil
+}
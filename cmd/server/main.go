@@ -15,6 +15,14 @@ import (
 	"gin-starter/internal/middleware"
 	"gin-starter/internal/routes"
 	"gin-starter/internal/service/image"
+	"gin-starter/internal/service/media"
+	"gin-starter/internal/service/notify"
+	"gin-starter/internal/service/ratelimit"
+	"gin-starter/internal/service/robots"
+	"gin-starter/internal/service/sitemap"
+	"gin-starter/internal/siteurl"
+	"gin-starter/templates/components"
+	"gin-starter/templates/layouts/header"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,8 +31,69 @@ func main() {
 	// 1. Конфиг
 	cfg := config.LoadConfig()
 
+	// Контекст фоновой обработки изображений: его отмена в начале остановки
+	// прерывает декодирование и кодирование. Контексты запросов от него не зависят
+	imageCtx, cancelImages := context.WithCancel(context.Background())
+	defer cancelImages()
+
 	// 2. Инициализация зависимостей
-	image.InitializeCache()
+	// Наложения и пресеты проверяются до запуска: ошибка в конфиге не должна всплыть на первом запросе
+	imageOverlays, err := image.NewOverlays(cfg.ImageWatermarks, cfg.ImageTextOverlays)
+	if err != nil {
+		log.Fatalf("invalid image overlays: %v", err)
+	}
+
+	imageProcessor, closeImages := newImageProcessor(imageCtx, cfg, imageOverlays)
+	defer closeImages()
+
+	imagePresets, err := image.ParsePresets(cfg.ImagePresets, imageOverlays)
+	if err != nil {
+		log.Fatalf("invalid IMAGE_PRESETS: %v", err)
+	}
+	log.Printf("Image presets: %d loaded", len(imagePresets.List()))
+
+	siteURL, err := siteurl.New(siteurl.Options{
+		BaseURL:         cfg.PublicBaseURL,
+		CanonicalParams: cfg.CanonicalQueryParams,
+	})
+	if err != nil {
+		log.Fatalf("invalid PUBLIC_BASE_URL: %v", err)
+	}
+
+	robotsRules, err := robots.ParseRules(cfg.RobotsRules)
+	if err != nil {
+		log.Fatalf("invalid ROBOTS_RULES: %v", err)
+	}
+	if !cfg.IsProduction() {
+		log.Printf("Environment %q: robots.txt disallows indexing", cfg.AppEnv)
+	}
+
+	contactNotifier, err := notify.New(cfg.ContactNotifiers, notify.Options{
+		SMTP: notify.SMTPOptions{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+			To:       cfg.ContactEmailTo,
+		},
+		WebhookURL: cfg.ContactWebhookURL,
+	})
+	if err != nil {
+		log.Fatalf("invalid CONTACT_NOTIFIERS: %v", err)
+	}
+	log.Printf("Contact notifiers: %v", cfg.ContactNotifiers)
+
+	// Команда "warmup": прогреваем кэш изображений и выходим, сервер не запускается
+	if len(os.Args) > 1 && os.Args[1] == "warmup" {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		if err := runWarmup(ctx, cfg, imageProcessor, imagePresets); err != nil {
+			log.Printf("Image warm-up failed: %v", err)
+		}
+		return
+	}
+
 	dbStore, cleanupFunc := database.InitDatabase(cfg)
 	// Этот defer сработает только при штатном выходе из main, но для Graceful Shutdown нужно больше
 	defer cleanupFunc()
@@ -36,17 +105,66 @@ func main() {
 	}
 
 	// 3. Роутер
-	r := gin.Default()
+	// Вместо gin.Default: паники перехватывает свой RecoveryMiddleware, который
+	// отвечает страницей ошибки или problem+json с id запроса
+	r, err := newRouter(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
+	r.Use(gin.Logger())
+	r.Use(middleware.RequestIDMiddleware())
 	r.Use(middleware.LoggerMiddleware())
+	r.Use(middleware.RecoveryMiddleware(handlers.RenderError))
 	r.Use(middleware.CORSMiddleware())
+	r.Use(middleware.SiteURLMiddleware(siteURL, cfg.CanonicalRedirect))
+	r.Use(middleware.LocaleMiddleware())
 
-	// Статика
-	r.StaticFile("/robots.txt", "./static/robots.txt")
-	r.StaticFile("/sitemap.xml", "./static/sitemap.xml")
-	r.Static("/static", "./static")
+	// Статика: каталог медиа публикуется как /static/ вместе с css и js,
+	// поэтому загрузки и исходники изображений доступны по тем же ссылкам
+	r.Static("/static", cfg.ImageMediaDir)
 
 	// 4. Сервисы и Хендлеры (DI)
-	imageProcessor := image.NewProcessorService()
+	// Компоненты изображений получают сервис и пресеты через контекст рендеринга
+	images := components.Images{Service: imageProcessor, Presets: imagePresets}
+	r.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(components.WithImages(c.Request.Context(), images))
+		c.Next()
+	})
+
+	// Меню сайта собирается один раз; страница пользователей без базы данных
+	// не работает, поэтому и в меню ее тогда нет
+	hasDatabase := dbStore != nil
+	header.SetMenu(header.NewMenu(
+		header.MenuEntry{Path: "/", TextKey: "menu.home"},
+		header.MenuEntry{Path: "/about", TextKey: "menu.about"},
+		header.MenuEntry{Path: "/contact", TextKey: "menu.contact"},
+		header.MenuEntry{Path: "/users", TextKey: "menu.users", Visible: func(context.Context) bool { return hasDatabase }},
+	))
+
+	// Загруженные изображения в локальном хранилище попадают в каталог медиа
+	// и сразу доступны по /static/uploads/... и через обработчик изображений
+	var mediaStorage media.Storage
+	switch cfg.MediaStorage {
+	case "s3":
+		mediaStorage, err = media.NewS3Storage(media.S3Options{
+			Endpoint:  cfg.MediaS3Endpoint,
+			Region:    cfg.MediaS3Region,
+			Bucket:    cfg.MediaS3Bucket,
+			AccessKey: cfg.MediaS3AccessKey,
+			SecretKey: cfg.MediaS3SecretKey,
+			PublicURL: cfg.MediaS3PublicURL,
+		})
+	default:
+		mediaStorage, err = media.NewLocalStorage(cfg.ImageMediaDir, image.MediaURLPrefix)
+	}
+	if err != nil {
+		log.Fatalf("failed to init media storage: %v", err)
+	}
+	uploader := media.NewUploader(mediaStorage, media.UploadOptions{
+		MaxBytes:      cfg.MediaMaxBytes,
+		MaxDimension:  cfg.MediaMaxDimension,
+		MaxMegapixels: cfg.ImageMaxMegapixels,
+	})
 
 	// Внедряем dbStore в контекст для доступа в хендлерах
 	if dbStore != nil {
@@ -59,16 +177,26 @@ func main() {
 	// Создаем обработчики
 	pageHandler := handlers.NewPageHandler()
 	userHandler := handlers.NewUserHandler()
-	imageHandler := handlers.NewImageHandler(imageProcessor)
+	imageHandler := handlers.NewImageHandler(imageProcessor, imagePresets, cfg.ImagePresetsOnly)
+	mediaHandler := handlers.NewMediaHandler(uploader)
+	contactHandler := handlers.NewContactHandler(contactNotifier,
+		ratelimit.New(cfg.ContactRateLimit, cfg.ContactRateWindow), cfg.ContactNotifyTimeout)
+
+	// Карта сайта: страницы регистрируются вместе с маршрутами, динамические ссылки - источниками
+	siteMap := sitemap.New(cfg.SitemapCacheTTL)
+	if dbStore != nil {
+		siteMap.AddSource(handlers.UsersSitemapSource(dbStore))
+	}
+	sitemapHandler := handlers.NewSitemapHandler(siteMap)
+	robotsHandler := handlers.NewRobotsHandler(robotsRules, cfg.IsProduction())
 
 	// 5. Маршруты
-	routes.SetupRoutes(r, pageHandler, userHandler, imageHandler)
+	routes.SetupRoutes(r, pageHandler, userHandler, contactHandler, imageHandler, mediaHandler, sitemapHandler, robotsHandler,
+		middleware.AdminAuthMiddleware(cfg.AdminToken, handlers.RenderError),
+		middleware.AdminPageAuthMiddleware(cfg.AdminToken, handlers.RenderError))
 
 	// 6. Запуск сервера с Graceful Shutdown
-	srv := &http.Server{
-		Addr:    ":" + cfg.ServerPort,
-		Handler: r,
-	}
+	srv := newHTTPServer(":"+cfg.ServerPort, r)
 
 	// Запускаем сервер в горутине, чтобы он не блокировал main
 	go func() {
@@ -78,17 +206,26 @@ func main() {
 		}
 	}()
 
+	// Прогрев кэша изображений идет в фоне и не задерживает старт сервера
+	warmupCtx, stopWarmup := context.WithCancel(context.Background())
+	defer stopWarmup()
+	if cfg.ImageWarmupOnStart {
+		go func() {
+			if err := runWarmup(warmupCtx, cfg, imageProcessor, imagePresets); err != nil && warmupCtx.Err() == nil {
+				log.Printf("⚠️ Warning: image warm-up failed: %v", err)
+			}
+		}()
+	}
+
 	// Ждем сигнала прерывания (Ctrl+C, Docker stop)
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("🛑 Shutting down server...")
+	stopWarmup()
 
-	// Даем серверу 5 секунд на завершение текущих запросов
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := srv.Shutdown(ctx); err != nil {
+	// Обработка изображений прерывается сразу, остальным запросам даем 5 секунд на завершение
+	if err := shutdownServer(srv, cancelImages, 5*time.Second); err != nil {
 		log.Fatal("Server forced to shutdown:", err)
 	}
 
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newRouter создает роутер. X-Forwarded-For учитывается только от доверенных
+// прокси (trustedProxies): по умолчанию gin верит любому отправителю, и клиент
+// подставил бы любой IP, обходя ограничения частоты по адресу
+func newRouter(trustedProxies []string) (*gin.Engine, error) {
+	r := gin.New()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// newHTTPServer создает HTTP-сервер приложения. Контексты запросов растут из
+// context.Background, а не из контекста обработки изображений: остановка не
+// обрывает запись в базу и другие текущие запросы
+func newHTTPServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:    addr,
+		Handler: handler,
+	}
+}
+
+// shutdownServer останавливает сервер: cancelImages сразу прерывает обработку
+// изображений, а текущие запросы Shutdown дожидается не дольше timeout
+func shutdownServer(srv *http.Server, cancelImages context.CancelFunc, timeout time.Duration) error {
+	cancelImages()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gin-starter/internal/handlers"
+	"gin-starter/internal/service/notify"
+	"gin-starter/internal/service/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestShutdownServerDrainsRequests(t *testing.T) {
+	imageCtx, cancelImages := context.WithCancel(context.Background())
+	defer cancelImages()
+
+	started := make(chan struct{})
+	requestErr := make(chan error, 1)
+	srv := newHTTPServer("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		// Медленный запрос (например, запись в базу) идет дольше начала остановки
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+		requestErr <- r.Context().Err()
+		_, _ = io.WriteString(w, "done")
+	}))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	response := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err != nil {
+			response <- result{err: err}
+			return
+		}
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		body, err := io.ReadAll(resp.Body)
+		response <- result{status: resp.StatusCode, body: string(body), err: err}
+	}()
+	<-started
+
+	if err := shutdownServer(srv, cancelImages, 5*time.Second); err != nil {
+		t.Fatalf("shutdownServer: %v", err)
+	}
+	if imageCtx.Err() == nil {
+		t.Error("image processing context is not cancelled on shutdown")
+	}
+	if err := <-requestErr; err != nil {
+		t.Errorf("request context = %v during shutdown, want it alive", err)
+	}
+	res := <-response
+	if res.err != nil || res.status != http.StatusOK || res.body != "done" {
+		t.Fatalf("slow request = %d %q, %v; want 200 \"done\"", res.status, res.body, res.err)
+	}
+}
+
+// submitContact отправляет форму обратной связи с адреса remoteAddr и заголовком X-Forwarded-For
+func submitContact(r *gin.Engine, remoteAddr, forwardedFor string) int {
+	form := url.Values{"name": {"Иван"}, "email": {"ivan@example.com"}, "message": {"Здравствуйте!"}}
+	req := httptest.NewRequest(http.MethodPost, "/contact", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+// newContactRouter роутер с формой обратной связи: одно сообщение с IP в час
+func newContactRouter(t *testing.T, trustedProxies []string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r, err := newRouter(trustedProxies)
+	if err != nil {
+		t.Fatalf("newRouter: %v", err)
+	}
+	contact := handlers.NewContactHandler(notify.Multi{}, ratelimit.New(1, time.Hour), time.Second)
+	r.POST("/contact", contact.Submit)
+	return r
+}
+
+func TestRouterIgnoresSpoofedForwardedFor(t *testing.T) {
+	r := newContactRouter(t, nil)
+
+	if code := submitContact(r, "198.51.100.7:1234", "203.0.113.1"); code != http.StatusSeeOther {
+		t.Fatalf("first submit = %d, want 303", code)
+	}
+	// Новый X-Forwarded-For от того же клиента не дает новой попытки
+	if code := submitContact(r, "198.51.100.7:1234", "203.0.113.2"); code != http.StatusTooManyRequests {
+		t.Fatalf("submit with spoofed X-Forwarded-For = %d, want 429", code)
+	}
+}
+
+func TestRouterTrustsConfiguredProxies(t *testing.T) {
+	r := newContactRouter(t, []string{"192.0.2.0/24"})
+
+	// За доверенным прокси разные клиенты различаются по X-Forwarded-For
+	for _, client := range []string{"203.0.113.1", "203.0.113.2"} {
+		if code := submitContact(r, "192.0.2.10:1234", client); code != http.StatusSeeOther {
+			t.Fatalf("submit from %s via trusted proxy = %d, want 303", client, code)
+		}
+	}
+	if code := submitContact(r, "192.0.2.10:1234", "203.0.113.1"); code != http.StatusTooManyRequests {
+		t.Fatalf("repeated submit from 203.0.113.1 = %d, want 429", code)
+	}
+}
+
+func TestNewRouterRejectsInvalidProxies(t *testing.T) {
+	if _, err := newRouter([]string{"not-an-ip"}); err == nil {
+		t.Fatal("newRouter(not-an-ip) = nil error, want invalid proxy")
+	}
+}
@@ -3,20 +3,101 @@ package config
 import (
 	"log"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 // Config структура для хранения конфигурации приложения
 type Config struct {
+	AppEnv     string // development, staging или production; индексировать сайт можно только в production
 	ServerPort string
-	DBType     string // "postgres" или "sqlite"
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	DBPath     string // Путь к файлу SQLite
+	// TrustedProxies адреса и подсети прокси, от которых принимается X-Forwarded-For;
+	// пусто - IP клиента берется из соединения
+	TrustedProxies []string
+	DBType         string // "postgres" или "sqlite"
+	DBHost         string
+	DBPort         string
+	DBUser         string
+	DBPassword     string
+	DBName         string
+	DBPath         string // Путь к файлу SQLite
+
+	AdminToken string // токен служебных эндпоинтов /api/v1/admin; пусто - эндпоинты выключены
+
+	// PublicBaseURL публичный адрес сайта (https://example.com) для canonical и
+	// абсолютных ссылок в sitemap.xml и robots.txt; пусто - адрес берется из запроса
+	PublicBaseURL        string
+	CanonicalQueryParams []string      // параметры запроса, которые остаются в canonical
+	CanonicalRedirect    bool          // 301 с "/about/" и "//about" на канонический путь
+	SitemapCacheTTL      time.Duration // как долго собранная карта сайта отдается без пересборки
+	RobotsRules          string        // правила robots.txt: "*=disallow:/api/; GPTBot=disallow:/"
+
+	// Кэш обработанных изображений
+	ImageCacheTTL         time.Duration // время жизни элемента в памяти
+	ImageCacheMemoryBytes int64         // бюджет памяти в байтах
+	ImageCacheDiskDir     string        // каталог дискового кэша; пусто - диск не используется
+	ImageCacheDiskBytes   int64         // бюджет диска в байтах
+
+	// Обработка изображений
+	ImageMediaDir          string        // каталог исходников, публикуется как /static/
+	ImageAllowedExtensions []string      // разрешенные расширения исходников
+	ImageMaxMegapixels     float64       // предельный размер исходника в мегапикселях
+	ImageMaxFrames         int           // предельное число кадров анимированного GIF
+	ImageMaxAnimationMP    float64       // предельная суммарная площадь кадров анимации в мегапикселях
+	ImageMaxConcurrent     int           // сколько изображений декодируется одновременно
+	ImageQueueTimeout      time.Duration // сколько запрос ждет свободного обработчика до ответа 503
+	ImageProcessTimeout    time.Duration // предельное время обработки одного варианта
+	ImageMemoryBudget      int64         // оценка памяти на все идущие обработки в байтах
+
+	// Удаленные исходники изображений (http/https)
+	ImageRemoteAllowedHosts []string      // разрешенные хосты; пусто - удаленные исходники выключены
+	ImageRemoteMaxBytes     int64         // предельный размер ответа origin
+	ImageRemoteTimeout      time.Duration // таймаут запроса к origin
+	ImageRemoteTTL          time.Duration // как долго скачанный исходник считается свежим
+
+	// Пресеты изображений (/img/{preset}/{path})
+	ImagePresets     string // пресеты: "thumb=w:150,h:150,fit:cover,fm:auto,q:75; hero=w:1200"
+	ImagePresetsOnly bool   // /optimized-image принимает только параметры пресетов
+	// Наложения для пресетов (wm:имя, text:имя)
+	ImageWatermarks   string // водяные знаки: "brand=file:./assets/logo.png,pos:bottom-right,opacity:0.6"
+	ImageTextOverlays string // надписи: "copyright=text:© Example,pos:bottom-left,size:0.04"
+
+	// Прогрев кэша изображений: при старте в фоне или командой "server warmup"
+	ImageWarmupOnStart     bool     // запускать прогрев в фоне при старте сервера
+	ImageWarmupManifest    string   // файл со списком путей; пусто - обход ImageWarmupDir
+	ImageWarmupDir         string   // каталог внутри ImageMediaDir для обхода
+	ImageWarmupWidths      []int    // ширины вариантов
+	ImageWarmupFormats     []string // форматы вариантов; пусто - как в шаблонах: по умолчанию, AVIF и WebP
+	ImageWarmupQuality     int      // качество вариантов (как в шаблонах)
+	ImageWarmupConcurrency int      // сколько вариантов готовится одновременно
+
+	// Загрузка изображений
+	MediaStorage      string // хранилище: local (каталог ImageMediaDir) или s3
+	MediaMaxBytes     int64  // предельный размер загружаемого файла
+	MediaMaxDimension int    // предельная ширина и высота в пикселях
+	MediaS3Endpoint   string // адрес S3-совместимого API
+	MediaS3Region     string
+	MediaS3Bucket     string
+	MediaS3AccessKey  string
+	MediaS3SecretKey  string
+	MediaS3PublicURL  string // публичный адрес бакета (CDN); пусто - Endpoint/Bucket
+
+	// Форма обратной связи (/contact)
+	ContactNotifiers     []string      // куда уведомлять о новых сообщениях: log, smtp, webhook
+	ContactNotifyTimeout time.Duration // предельное время доставки одного уведомления
+	ContactRateLimit     int           // сколько сообщений принимается с одного IP за окно
+	ContactRateWindow    time.Duration // окно ограничения частоты
+	ContactWebhookURL    string        // адрес для POST с JSON сообщения (notifier webhook)
+	ContactEmailTo       []string      // получатели писем (notifier smtp)
+	SMTPHost             string
+	SMTPPort             int // 465 - TLS сразу, иначе STARTTLS, если сервер его предлагает
+	SMTPUsername         string
+	SMTPPassword         string
+	SMTPFrom             string // адрес отправителя писем
 }
 
 // LoadConfig загружает конфигурацию из переменных окружения
@@ -27,14 +108,79 @@ func LoadConfig() *Config {
 	}
 
 	config := &Config{
-		ServerPort: getEnvOrDefault("SERVER_PORT", "8080"),
-		DBType:     getEnvOrDefault("DB_TYPE", "sqlite"), // По умолчанию используем SQLite
-		DBHost:     getEnvOrDefault("DB_HOST", "localhost"),
-		DBPort:     getEnvOrDefault("DB_PORT", "5432"),
-		DBUser:     getEnvOrDefault("DB_USER", "postgres"),
-		DBPassword: getEnvOrDefault("DB_PASSWORD", ""),
-		DBName:     getEnvOrDefault("DB_NAME", "gin_starter"),
-		DBPath:     getEnvOrDefault("DB_PATH", "./data.db"), // Путь к файлу SQLite
+		AppEnv:         getEnvOrDefault("APP_ENV", "development"),
+		ServerPort:     getEnvOrDefault("SERVER_PORT", "8080"),
+		TrustedProxies: getEnvList("TRUSTED_PROXIES", nil),
+		DBType:         getEnvOrDefault("DB_TYPE", "sqlite"), // По умолчанию используем SQLite
+		DBHost:         getEnvOrDefault("DB_HOST", "localhost"),
+		DBPort:         getEnvOrDefault("DB_PORT", "5432"),
+		DBUser:         getEnvOrDefault("DB_USER", "postgres"),
+		DBPassword:     getEnvOrDefault("DB_PASSWORD", ""),
+		DBName:         getEnvOrDefault("DB_NAME", "gin_starter"),
+		DBPath:         getEnvOrDefault("DB_PATH", "./data.db"), // Путь к файлу SQLite
+
+		AdminToken: getEnvOrDefault("ADMIN_TOKEN", ""),
+
+		PublicBaseURL:        getEnvOrDefault("PUBLIC_BASE_URL", ""),
+		CanonicalQueryParams: getEnvList("CANONICAL_QUERY_PARAMS", []string{"page", "q"}),
+		CanonicalRedirect:    getEnvBool("CANONICAL_REDIRECT", true),
+		SitemapCacheTTL:      getEnvDuration("SITEMAP_CACHE_TTL", 10*time.Minute),
+		RobotsRules:          getEnvOrDefault("ROBOTS_RULES", "*=disallow:/api/,disallow:/optimized-image"),
+
+		ImageCacheTTL:         getEnvDuration("IMAGE_CACHE_TTL", time.Hour),
+		ImageCacheMemoryBytes: getEnvInt64("IMAGE_CACHE_MEMORY_BYTES", 64<<20), // 64 МБ
+		ImageCacheDiskDir:     lookupEnvOrDefault("IMAGE_CACHE_DISK_DIR", "./data/image-cache"),
+		ImageCacheDiskBytes:   getEnvInt64("IMAGE_CACHE_DISK_BYTES", 1<<30), // 1 ГБ
+
+		ImageMediaDir:          getEnvOrDefault("IMAGE_MEDIA_DIR", "./static"),
+		ImageAllowedExtensions: getEnvList("IMAGE_ALLOWED_EXTENSIONS", []string{".jpg", ".jpeg", ".png", ".gif", ".webp"}),
+		ImageMaxMegapixels:     getEnvFloat("IMAGE_MAX_MEGAPIXELS", 40),
+		ImageMaxFrames:         int(getEnvInt64("IMAGE_MAX_FRAMES", 300)),
+		ImageMaxAnimationMP:    getEnvFloat("IMAGE_MAX_ANIMATION_MEGAPIXELS", 50),
+		ImageMaxConcurrent:     int(getEnvInt64("IMAGE_MAX_CONCURRENT", int64(runtime.NumCPU()))),
+		ImageQueueTimeout:      getEnvDuration("IMAGE_QUEUE_TIMEOUT", 5*time.Second),
+		ImageProcessTimeout:    getEnvDuration("IMAGE_PROCESS_TIMEOUT", 30*time.Second),
+		ImageMemoryBudget:      getEnvInt64("IMAGE_MEMORY_BUDGET_BYTES", 1<<30), // 1 ГБ
+
+		ImageRemoteAllowedHosts: getEnvList("IMAGE_REMOTE_ALLOWED_HOSTS", nil),
+		ImageRemoteMaxBytes:     getEnvInt64("IMAGE_REMOTE_MAX_BYTES", 20<<20), // 20 МБ
+		ImageRemoteTimeout:      getEnvDuration("IMAGE_REMOTE_TIMEOUT", 10*time.Second),
+		ImageRemoteTTL:          getEnvDuration("IMAGE_REMOTE_TTL", time.Hour),
+
+		ImagePresets:      getEnvOrDefault("IMAGE_PRESETS", "thumb=w:150,h:150,fit:cover,fm:auto,q:75; sm=w:150,q:80; md=w:300,q:80; card=w:600,q:80; hero=w:1200,q:80; og=w:1200,h:630,fit:cover,q:85,fm:jpeg"),
+		ImagePresetsOnly:  getEnvBool("IMAGE_PRESETS_ONLY", false),
+		ImageWatermarks:   getEnvOrDefault("IMAGE_WATERMARKS", ""),
+		ImageTextOverlays: getEnvOrDefault("IMAGE_TEXT_OVERLAYS", ""),
+
+		ImageWarmupOnStart:     getEnvBool("IMAGE_WARMUP_ON_START", false),
+		ImageWarmupManifest:    getEnvOrDefault("IMAGE_WARMUP_MANIFEST", ""),
+		ImageWarmupDir:         getEnvOrDefault("IMAGE_WARMUP_DIR", "images"),
+		ImageWarmupWidths:      getEnvIntList("IMAGE_WARMUP_WIDTHS", []int{150, 300, 600}),
+		ImageWarmupFormats:     getEnvList("IMAGE_WARMUP_FORMATS", nil),
+		ImageWarmupQuality:     int(getEnvInt64("IMAGE_WARMUP_QUALITY", 80)),
+		ImageWarmupConcurrency: int(getEnvInt64("IMAGE_WARMUP_CONCURRENCY", 1)),
+
+		MediaStorage:      getEnvOrDefault("MEDIA_STORAGE", "local"),
+		MediaMaxBytes:     getEnvInt64("MEDIA_MAX_BYTES", 10<<20), // 10 МБ
+		MediaMaxDimension: int(getEnvInt64("MEDIA_MAX_DIMENSION", 8192)),
+		MediaS3Endpoint:   getEnvOrDefault("MEDIA_S3_ENDPOINT", ""),
+		MediaS3Region:     getEnvOrDefault("MEDIA_S3_REGION", "us-east-1"),
+		MediaS3Bucket:     getEnvOrDefault("MEDIA_S3_BUCKET", ""),
+		MediaS3AccessKey:  getEnvOrDefault("MEDIA_S3_ACCESS_KEY", ""),
+		MediaS3SecretKey:  getEnvOrDefault("MEDIA_S3_SECRET_KEY", ""),
+		MediaS3PublicURL:  getEnvOrDefault("MEDIA_S3_PUBLIC_URL", ""),
+
+		ContactNotifiers:     getEnvList("CONTACT_NOTIFIERS", []string{"log"}),
+		ContactNotifyTimeout: getEnvDuration("CONTACT_NOTIFY_TIMEOUT", 10*time.Second),
+		ContactRateLimit:     int(getEnvInt64("CONTACT_RATE_LIMIT", 5)),
+		ContactRateWindow:    getEnvDuration("CONTACT_RATE_WINDOW", time.Hour),
+		ContactWebhookURL:    getEnvOrDefault("CONTACT_WEBHOOK_URL", ""),
+		ContactEmailTo:       getEnvList("CONTACT_EMAIL_TO", nil),
+		SMTPHost:             getEnvOrDefault("SMTP_HOST", ""),
+		SMTPPort:             int(getEnvInt64("SMTP_PORT", 587)),
+		SMTPUsername:         getEnvOrDefault("SMTP_USERNAME", ""),
+		SMTPPassword:         getEnvOrDefault("SMTP_PASSWORD", ""),
+		SMTPFrom:             getEnvOrDefault("SMTP_FROM", ""),
 	}
 
 	return config
@@ -47,3 +193,106 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// lookupEnvOrDefault как getEnvOrDefault, но явно заданная пустая переменная
+// возвращается как есть - так настройку можно отключить
+func lookupEnvOrDefault(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvInt64 читает целое число из переменной окружения
+func getEnvInt64(key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvFloat читает дробное число из переменной окружения
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using default %g", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvList читает список значений через запятую из переменной окружения
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// getEnvBool читает логическое значение (true/false, 1/0) из переменной окружения
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using default %t", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvIntList читает список целых чисел через запятую из переменной окружения
+func getEnvIntList(key string, defaultValue []int) []int {
+	items := getEnvList(key, nil)
+	if len(items) == 0 {
+		return defaultValue
+	}
+	result := make([]int, 0, len(items))
+	for _, item := range items {
+		parsed, err := strconv.Atoi(item)
+		if err != nil {
+			log.Printf("Warning: invalid %s=%q, using default %v", key, os.Getenv(key), defaultValue)
+			return defaultValue
+		}
+		result = append(result, parsed)
+	}
+	return result
+}
+
+// getEnvDuration читает длительность (например, "1h" или "30m") из переменной окружения
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using default %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// IsProduction сообщает, запущено ли приложение в production
+func (c *Config) IsProduction() bool {
+	return c.AppEnv == "production"
+}
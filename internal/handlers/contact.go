@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"gin-starter/internal/i18n"
+	"gin-starter/internal/models"
+	"gin-starter/internal/service/notify"
+	"gin-starter/internal/service/ratelimit"
+	"gin-starter/internal/store"
+	"gin-starter/templates"
+	"log"
+	"math"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contactMessagesPerPage сообщений на странице списка в админке
+const contactMessagesPerPage = 20
+
+// maxContactBodyBytes предельный размер тела формы обратной связи
+const maxContactBodyBytes = 64 << 10
+
+// maxUserAgentLength сколько символов User-Agent сохраняется с сообщением
+const maxUserAgentLength = 500
+
+// ContactHandler обработчики формы обратной связи и списка сообщений в админке
+type ContactHandler struct {
+	notifier      notify.Notifier
+	limiter       *ratelimit.Limiter
+	notifyTimeout time.Duration
+}
+
+// NewContactHandler создает новый экземпляр ContactHandler. limiter ограничивает
+// число принятых сообщений с одного IP, notifyTimeout - время доставки уведомления
+func NewContactHandler(notifier notify.Notifier, limiter *ratelimit.Limiter, notifyTimeout time.Duration) *ContactHandler {
+	return &ContactHandler{
+		notifier:      notifier,
+		limiter:       limiter,
+		notifyTimeout: notifyTimeout,
+	}
+}
+
+// Show обработчик для страницы "Контакты"; ?sent=1 - благодарность после отправки
+func (h *ContactHandler) Show(c *gin.Context) {
+	h.render(c, http.StatusOK, templates.ContactForm{Sent: c.Query("sent") == "1"})
+}
+
+// Submit обработчик формы обратной связи (POST /contact). Ошибки проверки
+// возвращают страницу с формой и статусом 422, принятое сообщение сохраняется,
+// уведомление уходит в фоне, а браузер получает редирект на ?sent=1, чтобы
+// обновление страницы не отправило форму повторно
+func (h *ContactHandler) Submit(c *gin.Context) {
+	ctx := c.Request.Context()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxContactBodyBytes)
+
+	form := templates.ContactForm{
+		Name:    strings.TrimSpace(c.PostForm("name")),
+		Email:   strings.TrimSpace(c.PostForm("email")),
+		Message: strings.TrimSpace(strings.ReplaceAll(c.PostForm("message"), "\r\n", "\n")),
+	}
+
+	// Бот заполнил поле-ловушку: отвечаем как на успех, чтобы он не подбирал обход
+	if c.PostForm(templates.ContactHoneypotField) != "" {
+		log.Printf("Contact form: honeypot filled from %s, message dropped", c.ClientIP())
+		h.redirectSent(c)
+		return
+	}
+
+	form.Errors = validateContactForm(ctx, form)
+	if len(form.Errors) > 0 {
+		h.render(c, http.StatusUnprocessableEntity, form)
+		return
+	}
+
+	// Считаются только прошедшие проверку сообщения: опечатка в адресе не отнимает попытку
+	if ok, retryAfter := h.limiter.Allow(c.ClientIP()); !ok {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		form.Error = i18n.T(ctx, "contact.rate_limited")
+		h.render(c, http.StatusTooManyRequests, form)
+		return
+	}
+
+	message := &models.ContactMessage{
+		Name:      form.Name,
+		Email:     form.Email,
+		Message:   form.Message,
+		IP:        c.ClientIP(),
+		UserAgent: truncateRunes(c.Request.UserAgent(), maxUserAgentLength),
+		Locale:    i18n.FromContext(ctx),
+		CreatedAt: time.Now(),
+	}
+	if err := saveContactMessage(c, message); err != nil {
+		log.Printf("Error saving contact message: %v", err)
+		form.Error = i18n.T(ctx, "contact.send_failed")
+		h.render(c, http.StatusInternalServerError, form)
+		return
+	}
+
+	h.notify(message)
+	h.redirectSent(c)
+}
+
+// AdminList обработчик списка сообщений в админке (?page=)
+func (h *ContactHandler) AdminList(c *gin.Context) {
+	// Получаем доступ к базе данных из контекста
+	dbStore, exists := c.Get("dbStore")
+	if !exists {
+		log.Println("Database connection not found in context")
+		RenderError(c, http.StatusServiceUnavailable, i18n.T(c.Request.Context(), "error.db_unavailable"))
+		return
+	}
+
+	// Приводим к нужному типу
+	store := dbStore.(store.Store)
+	repo := store.GetContactRepo()
+	if repo == nil {
+		RenderError(c, http.StatusNotImplemented, i18n.T(c.Request.Context(), "error.contact_unavailable"))
+		return
+	}
+
+	page := 1
+	if value := c.Query("page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			NotFoundHandler(c)
+			return
+		}
+		page = parsed
+	}
+
+	messages, total, err := repo.List(contactMessagesPerPage, (page-1)*contactMessagesPerPage)
+	if err != nil {
+		log.Printf("Error getting contact messages: %v", err)
+		RenderError(c, http.StatusInternalServerError, i18n.T(c.Request.Context(), "error.contact_messages_get_failed"))
+		return
+	}
+	list := templates.ContactMessageList{
+		Messages:   messages,
+		Page:       page,
+		TotalPages: (total + contactMessagesPerPage - 1) / contactMessagesPerPage,
+		Total:      total,
+	}
+	if page > 1 && page > list.TotalPages {
+		NotFoundHandler(c)
+		return
+	}
+
+	// Личные данные посетителей: не кэшируются и не индексируются
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex, nofollow")
+	c.Status(http.StatusOK)
+	if err := templates.AdminContactMessagesPage(adminContactMeta(c), list).Render(c.Request.Context(), c.Writer); err != nil {
+		log.Printf("Template render error: %v", err)
+	}
+}
+
+// render отдает страницу "Контакты" с формой
+func (h *ContactHandler) render(c *gin.Context, status int, form templates.ContactForm) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(status)
+	if err := templates.ContactPage(contactMeta(c), form).Render(c.Request.Context(), c.Writer); err != nil {
+		log.Printf("Template render error: %v", err)
+	}
+}
+
+// redirectSent редирект на страницу благодарности на языке формы
+func (h *ContactHandler) redirectSent(c *gin.Context) {
+	c.Redirect(http.StatusSeeOther, i18n.Path(c.Request.Context(), "/contact")+"?sent=1")
+}
+
+// notify отправляет уведомление в фоне: медленный SMTP не задерживает ответ,
+// а сообщение к этому моменту уже сохранено
+func (h *ContactHandler) notify(message *models.ContactMessage) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), h.notifyTimeout)
+		defer cancel()
+		if err := h.notifier.Notify(ctx, message); err != nil {
+			log.Printf("⚠️ Warning: contact message #%d notification failed: %v", message.ID, err)
+		}
+	}()
+}
+
+// saveContactMessage сохраняет сообщение в базу. Без базы (или без
+// репозитория сообщений) оно не теряется: уведомление все равно уходит
+func saveContactMessage(c *gin.Context, message *models.ContactMessage) error {
+	dbStore, exists := c.Get("dbStore")
+	if !exists {
+		log.Println("Contact message is not stored: database connection not found in context")
+		return nil
+	}
+	repo := dbStore.(store.Store).GetContactRepo()
+	if repo == nil {
+		log.Println("Contact message is not stored: repository is not available")
+		return nil
+	}
+	return repo.Create(message)
+}
+
+// validateContactForm проверяет форму обратной связи; ключи - имена полей
+func validateContactForm(ctx context.Context, form templates.ContactForm) map[string]string {
+	errs := make(map[string]string)
+
+	if form.Name == "" || utf8.RuneCountInString(form.Name) > templates.MaxContactNameLength ||
+		strings.IndexFunc(form.Name, unicode.IsControl) >= 0 {
+		errs["name"] = i18n.T(ctx, "contact.name_required", templates.MaxContactNameLength)
+	}
+
+	address, err := mail.ParseAddress(form.Email)
+	if err != nil || address.Address != form.Email || len(form.Email) > 254 {
+		errs["email"] = i18n.T(ctx, "contact.email_invalid")
+	}
+
+	switch length := utf8.RuneCountInString(form.Message); {
+	case length == 0:
+		errs["message"] = i18n.T(ctx, "contact.message_required")
+	case length > templates.MaxContactMessageLength:
+		errs["message"] = i18n.T(ctx, "contact.message_too_long", templates.MaxContactMessageLength)
+	}
+
+	return errs
+}
+
+// truncateRunes обрезает строку до max символов
+func truncateRunes(s string, max int) string {
+	if runes := []rune(s); len(runes) > max {
+		return string(runes[:max])
+	}
+	return s
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gin-starter/internal/models"
+	"gin-starter/internal/service/ratelimit"
+	"gin-starter/internal/store"
+	"gin-starter/internal/testutil"
+	"gin-starter/templates"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordingNotifier передает полученные сообщения в канал: уведомление уходит в фоне
+type recordingNotifier struct {
+	messages chan *models.ContactMessage
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, message *models.ContactMessage) error {
+	n.messages <- message
+	return nil
+}
+
+// contactTestEnv роутер формы обратной связи поверх временной базы
+type contactTestEnv struct {
+	router   *gin.Engine
+	store    *store.SQLiteStore
+	notifier *recordingNotifier
+}
+
+// newContactTestEnv собирает маршрут формы с лимитом limit сообщений в час
+func newContactTestEnv(t *testing.T, limit int) *contactTestEnv {
+	t.Helper()
+	dbStore := testutil.NewSQLiteStore(t)
+
+	env := &contactTestEnv{
+		router:   testutil.NewRouter(dbStore),
+		store:    dbStore,
+		notifier: &recordingNotifier{messages: make(chan *models.ContactMessage, 10)},
+	}
+	handler := NewContactHandler(env.notifier, ratelimit.New(limit, time.Hour), time.Second)
+	env.router.POST("/contact", handler.Submit)
+	return env
+}
+
+// submit отправляет форму с адреса 192.0.2.1
+func (e *contactTestEnv) submit(t *testing.T, fields url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/contact", strings.NewReader(fields.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = "192.0.2.1:1234"
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, req)
+	return w
+}
+
+// storedMessages число сохраненных сообщений
+func (e *contactTestEnv) storedMessages(t *testing.T) int {
+	t.Helper()
+	_, total, err := e.store.GetContactRepo().List(10, 0)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	return total
+}
+
+// expectNoNotification проверяет, что уведомление не отправлялось
+func (e *contactTestEnv) expectNoNotification(t *testing.T) {
+	t.Helper()
+	select {
+	case message := <-e.notifier.messages:
+		t.Fatalf("unexpected notification for %+v", message)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// validContactForm корректно заполненная форма
+func validContactForm() url.Values {
+	return url.Values{
+		"name":    {"Иван"},
+		"email":   {"ivan@example.com"},
+		"message": {"Здравствуйте!"},
+	}
+}
+
+func TestContactSubmitStoresAndNotifies(t *testing.T) {
+	env := newContactTestEnv(t, 5)
+
+	w := env.submit(t, validContactForm())
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/contact?sent=1" {
+		t.Fatalf("submit = %d %q, want 303 to /contact?sent=1", w.Code, w.Header().Get("Location"))
+	}
+	if n := env.storedMessages(t); n != 1 {
+		t.Fatalf("stored %d messages, want 1", n)
+	}
+	select {
+	case message := <-env.notifier.messages:
+		if message.Email != "ivan@example.com" || message.IP != "192.0.2.1" {
+			t.Fatalf("notification = %+v, want the submitted message", message)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("notification was not sent")
+	}
+}
+
+func TestContactSubmitValidation(t *testing.T) {
+	tests := map[string]struct {
+		field, value string
+	}{
+		"empty name":       {"name", "  "},
+		"long name":        {"name", strings.Repeat("я", templates.MaxContactNameLength+1)},
+		"bad email":        {"email", "ivan@"},
+		"email with name":  {"email", "Ivan <ivan@example.com>"},
+		"empty message":    {"message", ""},
+		"too long message": {"message", strings.Repeat("a", templates.MaxContactMessageLength+1)},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			env := newContactTestEnv(t, 5)
+			form := validContactForm()
+			form.Set(tt.field, tt.value)
+
+			w := env.submit(t, form)
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("submit = %d, want 422", w.Code)
+			}
+			if n := env.storedMessages(t); n != 0 {
+				t.Fatalf("stored %d messages, want 0", n)
+			}
+			env.expectNoNotification(t)
+		})
+	}
+}
+
+func TestContactSubmitHoneypot(t *testing.T) {
+	env := newContactTestEnv(t, 5)
+	form := validContactForm()
+	form.Set(templates.ContactHoneypotField, "https://spam.example.com")
+
+	// Бот получает тот же ответ, что и человек, но сообщение отбрасывается
+	w := env.submit(t, form)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/contact?sent=1" {
+		t.Fatalf("submit = %d %q, want 303 to /contact?sent=1", w.Code, w.Header().Get("Location"))
+	}
+	if n := env.storedMessages(t); n != 0 {
+		t.Fatalf("stored %d messages, want 0", n)
+	}
+	env.expectNoNotification(t)
+}
+
+func TestContactSubmitRateLimit(t *testing.T) {
+	env := newContactTestEnv(t, 1)
+
+	// Ошибка проверки не отнимает попытку
+	invalid := validContactForm()
+	invalid.Set("email", "ivan@")
+	if w := env.submit(t, invalid); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid submit = %d, want 422", w.Code)
+	}
+	if w := env.submit(t, validContactForm()); w.Code != http.StatusSeeOther {
+		t.Fatalf("first submit = %d, want 303", w.Code)
+	}
+
+	w := env.submit(t, validContactForm())
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second submit = %d, want 429", w.Code)
+	}
+	if retry := w.Header().Get("Retry-After"); retry == "" || retry == "0" {
+		t.Fatalf("Retry-After = %q, want seconds until the window resets", retry)
+	}
+	if n := env.storedMessages(t); n != 1 {
+		t.Fatalf("stored %d messages, want 1", n)
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"gin-starter/templates"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mimeProblemJSON тип ответа с описанием ошибки по RFC 9457
+const mimeProblemJSON = "application/problem+json"
+
+// Problem описание ошибки для API (RFC 9457). Тип не задается ("about:blank"),
+// поэтому title - стандартная фраза статуса, а подробности - в detail
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// RenderError отвечает ошибкой в том виде, который ждет клиент: API и клиенты
+// без text/html в Accept получают application/problem+json, браузер - страницу
+// ошибки на языке запроса. Обработка запроса после этого прерывается.
+// detail - пояснение для пользователя, оно показывается как есть
+func RenderError(c *gin.Context, status int, detail string) {
+	c.Abort()
+	if c.Writer.Written() {
+		// Ответ уже начат (шаблон упал на середине): дописать ошибку некуда
+		log.Printf("Error %d after response started: %s %s", status, c.Request.Method, c.Request.URL.Path)
+		return
+	}
+
+	if wantsProblemJSON(c) {
+		c.Header("Content-Type", mimeProblemJSON)
+		c.JSON(status, Problem{
+			Type:      "about:blank",
+			Title:     http.StatusText(status),
+			Status:    status,
+			Detail:    detail,
+			Instance:  c.Request.URL.Path,
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(status)
+	view := templates.ErrorView{Status: status, Detail: detail, RequestID: requestID(c)}
+	if err := templates.ErrorPage(errorMeta(c, status), view).Render(c.Request.Context(), c.Writer); err != nil {
+		log.Printf("Template render error: %v", err)
+	}
+}
+
+// wantsProblemJSON ждет ли клиент JSON вместо страницы. Адреса /api/ всегда
+// получают JSON; остальным страница отдается, только если text/html в Accept
+// идет раньше JSON (так просит браузер), а не подходит лишь через "*/*"
+func wantsProblemJSON(c *gin.Context) bool {
+	if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		return true
+	}
+	return c.NegotiateFormat(mimeProblemJSON, gin.MIMEJSON, gin.MIMEHTML) != gin.MIMEHTML
+}
+
+// requestID id текущего запроса из RequestIDMiddleware; пусто, если его нет
+func requestID(c *gin.Context) string {
+	return c.GetString("requestID")
+}
@@ -1,9 +1,13 @@
 package handlers
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"log"
+	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"gin-starter/internal/service/image"
 
@@ -13,12 +17,17 @@ import (
 // ImageHandler структура для обработки запросов к изображениям
 type ImageHandler struct {
 	processor *image.ProcessorService
+	presets   *image.Presets
+	// presetsOnly запрещает в /optimized-image параметры, не совпадающие ни с одним пресетом
+	presetsOnly bool
 }
 
 // NewImageHandler создает новый экземпляр ImageHandler
-func NewImageHandler(processor *image.ProcessorService) *ImageHandler {
+func NewImageHandler(processor *image.ProcessorService, presets *image.Presets, presetsOnly bool) *ImageHandler {
 	return &ImageHandler{
-		processor: processor,
+		processor:   processor,
+		presets:     presets,
+		presetsOnly: presetsOnly,
 	}
 }
 
@@ -28,10 +37,13 @@ func (ih *ImageHandler) OptimizedImage(c *gin.Context) {
 	widthStr := c.Query("w")
 	heightStr := c.Query("h")
 	qualityStr := c.Query("q")
+	formatStr := c.Query("fm")
+	fitStr := c.Query("fit")
+	posterStr := c.Query("poster")
 
 	// Проверяем обязательный параметр path
 	if path == "" {
-		c.JSON(400, gin.H{"error": "path parameter is required"})
+		RenderError(c, 400, "path parameter is required")
 		return
 	}
 
@@ -42,7 +54,7 @@ func (ih *ImageHandler) OptimizedImage(c *gin.Context) {
 	if widthStr != "" {
 		width, err = strconv.Atoi(widthStr)
 		if err != nil || width <= 0 {
-			c.JSON(400, gin.H{"error": "invalid width parameter"})
+			RenderError(c, 400, "invalid width parameter")
 			return
 		}
 	}
@@ -50,7 +62,7 @@ func (ih *ImageHandler) OptimizedImage(c *gin.Context) {
 	if heightStr != "" {
 		height, err = strconv.Atoi(heightStr)
 		if err != nil || height <= 0 {
-			c.JSON(400, gin.H{"error": "invalid height parameter"})
+			RenderError(c, 400, "invalid height parameter")
 			return
 		}
 	}
@@ -58,33 +70,205 @@ func (ih *ImageHandler) OptimizedImage(c *gin.Context) {
 	if qualityStr != "" {
 		quality, err = strconv.Atoi(qualityStr)
 		if err != nil || quality <= 0 || quality > 100 {
-			c.JSON(400, gin.H{"error": "invalid quality parameter (1-100)"})
+			RenderError(c, 400, "invalid quality parameter (1-100)")
 			return
 		}
 	} else {
-		quality = 80 // значение по умолчанию
+		quality = image.DefaultQuality
 	}
 
-	// Проверяем, что путь начинается с /static для безопасности
-	if !strings.HasPrefix(path, "/static/") {
-		c.JSON(400, gin.H{"error": "path must start with /static/"})
+	format, err := image.ParseFormat(formatStr)
+	if err != nil {
+		RenderError(c, 400, "unsupported format parameter")
+		return
+	}
+
+	fit, err := image.ParseFit(fitStr)
+	if err != nil {
+		RenderError(c, 400, "invalid fit parameter (fill, cover, contain)")
+		return
+	}
+
+	// poster=1 отдает только первый кадр анимированного исходника
+	var poster bool
+	if posterStr != "" {
+		poster, err = strconv.ParseBool(posterStr)
+		if err != nil {
+			RenderError(c, 400, "invalid poster parameter")
+			return
+		}
+	}
+
+	opts := image.ProcessOptions{
+		Width:   width,
+		Height:  height,
+		Quality: quality,
+		Format:  format,
+		Fit:     fit,
+		Poster:  poster,
+	}
+
+	if ih.presetsOnly && !ih.presets.Allows(opts) {
+		RenderError(c, 403, "only preset variants are allowed")
+		return
+	}
+
+	ih.serveImage(c, path, opts)
+}
+
+// Preset отдает изображение в именованном пресете: /img/{preset}/{path...},
+// где path - путь внутри каталога медиа (как после /static/). Параметр fm
+// заменяет формат пресета: так <source> в <picture> получает AVIF и WebP
+func (ih *ImageHandler) Preset(c *gin.Context) {
+	preset, ok := ih.presets.Get(c.Param("preset"))
+	if !ok {
+		RenderError(c, 404, "unknown image preset")
 		return
 	}
 
-	// Убираем начальный слэш для формирования пути к файлу
-	filePath := "." + path
+	opts := preset.Options()
+	if formatStr := c.Query("fm"); formatStr != "" {
+		format, err := image.ParseFormat(formatStr)
+		if err != nil {
+			RenderError(c, 400, "unsupported format parameter")
+			return
+		}
+		opts.Format = format
+	}
+
+	path := image.MediaURLPrefix + strings.TrimPrefix(c.Param("path"), "/")
+	ih.serveImage(c, path, opts)
+}
+
+// ListPresets отдает список пресетов для админки
+func (ih *ImageHandler) ListPresets(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"presets":      ih.presets.List(),
+		"presets_only": ih.presetsOnly,
+	})
+}
+
+// serveImage отдает вариант изображения с валидаторами HTTP-кэша
+func (ih *ImageHandler) serveImage(c *gin.Context, path string, opts image.ProcessOptions) {
+	// Удаленный исходник скачивается один раз на запрос, даже если не помещается в кэш
+	ctx := image.WithOriginMemo(c.Request.Context())
+
+	// fm=auto: формат зависит от Accept, поэтому ответ кэшируется с Vary
+	if opts.Format == image.FormatAuto {
+		c.Header("Vary", "Accept")
+		format, err := ih.processor.NegotiateFormat(ctx, path, opts, c.GetHeader("Accept"))
+		if err != nil {
+			respondImageError(c, err)
+			return
+		}
+		opts.Format = format
+	}
 
-	// Получаем оптимизированное изображение
-	imgData, err := ih.processor.ProcessImage(c.Request.Context(), filePath, width, height, quality)
+	// Валидаторы считаются по метаданным файла, без обработки изображения.
+	// Здесь же проверяется путь: внутрь каталога медиа или на разрешенный хост
+	variant, err := ih.processor.Variant(ctx, path, opts)
 	if err != nil {
-		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to process image: %v", err)})
+		respondImageError(c, err)
 		return
 	}
 
-	// Устанавливаем заголовки
-	c.Header("Content-Type", "image/webp")
-	c.Header("Cache-Control", "public, max-age=3600") // кэшируем на 1 час
+	c.Header("ETag", variant.ETag)
+	c.Header("Last-Modified", variant.LastModified.Format(http.TimeFormat))
+	c.Header("Cache-Control", imageCacheControl(c.Query("v"), variant.Version))
+
+	if notModified(c.Request, variant.ETag, variant.LastModified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	// Получаем оптимизированное изображение в формате, выбранном для варианта
+	opts.Format = variant.Format
+	imgData, err := ih.processor.ProcessImage(ctx, path, opts)
+	if err != nil {
+		respondImageError(c, err)
+		return
+	}
 
 	// Отправляем изображение
-	c.Data(200, "image/webp", imgData)
+	c.Data(200, variant.Format.ContentType(), imgData)
+}
+
+// imageCacheControl выбирает политику кэширования. Ссылка с актуальной версией
+// исходника (?v=) никогда не указывает на другое содержимое, поэтому ее можно
+// кэшировать навсегда; остальные ссылки кэшируем на час и перепроверяем по ETag
+func imageCacheControl(requested, current string) string {
+	if requested != "" && requested == current {
+		return "public, max-age=31536000, immutable"
+	}
+	return "public, max-age=3600"
+}
+
+// notModified проверяет условные заголовки запроса (RFC 9110, раздел 13).
+// If-None-Match имеет приоритет: If-Modified-Since учитывается, только если его нет
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		since, err := http.ParseTime(ims)
+		if err == nil && !lastModified.After(since) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Placeholder отдает LQIP-превью и BlurHash исходного изображения
+func (ih *ImageHandler) Placeholder(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		RenderError(c, 400, "path parameter is required")
+		return
+	}
+
+	placeholder, err := ih.processor.Placeholder(c.Request.Context(), path)
+	if err != nil {
+		respondImageError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(200, placeholder)
+}
+
+// respondImageError переводит ошибки сервиса изображений в HTTP-ответы
+func respondImageError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, image.ErrInvalidPath), errors.Is(err, image.ErrExtensionNotAllowed):
+		RenderError(c, 400, err.Error())
+	case errors.Is(err, image.ErrNotFound):
+		RenderError(c, 404, "image not found")
+	case errors.Is(err, image.ErrHostNotAllowed):
+		RenderError(c, 403, err.Error())
+	case errors.Is(err, image.ErrImageTooLarge):
+		RenderError(c, 422, err.Error())
+	case errors.Is(err, image.ErrOriginFailed):
+		log.Printf("Image origin error: %v", err)
+		RenderError(c, 502, "failed to fetch image from origin")
+	case errors.Is(err, image.ErrBusy):
+		c.Header("Retry-After", "1")
+		RenderError(c, 503, err.Error())
+	case errors.Is(err, image.ErrProcessingTimeout):
+		log.Printf("Image processing timeout: %v", err)
+		RenderError(c, 503, err.Error())
+	case errors.Is(err, context.Canceled):
+		// Клиент ушел: отвечать некому, 499 (как в nginx) нужен только для логов
+		c.AbortWithStatus(499)
+	default:
+		log.Printf("Image processing error: %v", err)
+		RenderError(c, 500, "failed to process image")
+	}
 }
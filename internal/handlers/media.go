@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"gin-starter/internal/i18n"
+	"gin-starter/internal/models"
+	"gin-starter/internal/repository"
+	"gin-starter/internal/service/media"
+	"gin-starter/internal/store"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAltTextLength предельная длина альтернативного текста
+const maxAltTextLength = 500
+
+// multipartOverhead запас на заголовки multipart и текстовые поля формы
+const multipartOverhead = 1 << 20
+
+// MediaHandler обработчики загрузки изображений
+type MediaHandler struct {
+	uploader *media.Uploader
+}
+
+// NewMediaHandler создает новый экземпляр MediaHandler
+func NewMediaHandler(uploader *media.Uploader) *MediaHandler {
+	return &MediaHandler{uploader: uploader}
+}
+
+// Upload принимает multipart-форму с полями file и alt, сохраняет изображение
+// и запись о нем в таблице media. Маршрут закрыт служебным токеном (ADMIN_TOKEN),
+// входа пользователей в приложении нет, поэтому владельца у загрузки нет:
+// owner_id остается пустым, а значение из формы не принимается
+func (h *MediaHandler) Upload(c *gin.Context) {
+	// Получаем доступ к базе данных из контекста
+	dbStore, exists := c.Get("dbStore")
+	if !exists {
+		log.Println("Database connection not found in context")
+		RenderError(c, http.StatusServiceUnavailable, i18n.T(c.Request.Context(), "error.db_unavailable"))
+		return
+	}
+
+	// Приводим к нужному типу
+	store := dbStore.(store.Store)
+	if store.GetMediaRepo() == nil {
+		RenderError(c, http.StatusNotImplemented, i18n.T(c.Request.Context(), "error.media_unavailable"))
+		return
+	}
+
+	// Тело ограничиваем до разбора формы, иначе большой файл успеет лечь во временный каталог
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.uploader.MaxBytes()+multipartOverhead)
+
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			RenderError(c, http.StatusRequestEntityTooLarge, media.ErrTooLarge.Error())
+			return
+		}
+		RenderError(c, http.StatusBadRequest, i18n.T(c.Request.Context(), "error.file_required"))
+		return
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	altText := strings.TrimSpace(c.PostForm("alt"))
+	if utf8.RuneCountInString(altText) > maxAltTextLength {
+		RenderError(c, http.StatusBadRequest, i18n.T(c.Request.Context(), "error.alt_too_long"))
+		return
+	}
+
+	record := models.Media{AltText: altText}
+
+	upload, err := h.uploader.Upload(c.Request.Context(), file)
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	record.StorageKey = upload.Key
+	record.URL = upload.URL
+	record.ContentType = upload.ContentType
+	record.Size = upload.Size
+	record.Width = upload.Width
+	record.Height = upload.Height
+
+	if err := store.GetMediaRepo().Create(&record); err != nil {
+		log.Printf("Error creating media record: %v", err)
+		h.removeOrphan(c.Request.Context(), store.GetMediaRepo(), upload.Key)
+		RenderError(c, http.StatusInternalServerError, i18n.T(c.Request.Context(), "error.media_save_failed"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, record)
+}
+
+// removeOrphan удаляет файл, запись о котором не сохранилась: без нее файл
+// никто не найдет и не удалит. Одинаковое содержимое хранится под одним ключом,
+// поэтому файл, на который уже ссылается другая запись, остается. Если это
+// не удалось проверить, файл тоже остается: лишний файл лучше битой ссылки
+func (h *MediaHandler) removeOrphan(ctx context.Context, repo repository.MediaRepository, key string) {
+	inUse, err := repo.StorageKeyInUse(key)
+	if err != nil {
+		log.Printf("Keeping upload %s: %v", key, err)
+		return
+	}
+	if inUse {
+		return
+	}
+	// Контекст запроса может быть уже отменен, а удалить файл нужно в любом случае
+	if err := h.uploader.Delete(context.WithoutCancel(ctx), key); err != nil {
+		log.Printf("Error deleting orphaned upload %s: %v", key, err)
+	}
+}
+
+// respondUploadError переводит ошибки загрузки в HTTP-ответы
+func respondUploadError(c *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, media.ErrTooLarge), errors.As(err, &maxBytesErr):
+		RenderError(c, http.StatusRequestEntityTooLarge, media.ErrTooLarge.Error())
+	case errors.Is(err, media.ErrUnsupportedType):
+		RenderError(c, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, media.ErrInvalidImage):
+		RenderError(c, http.StatusBadRequest, media.ErrInvalidImage.Error())
+	case errors.Is(err, media.ErrDimensionsTooLarge):
+		RenderError(c, http.StatusUnprocessableEntity, err.Error())
+	default:
+		log.Printf("Media upload error: %v", err)
+		RenderError(c, http.StatusInternalServerError, i18n.T(c.Request.Context(), "error.upload_failed"))
+	}
+}
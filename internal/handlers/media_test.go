@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gin-starter/internal/middleware"
+	"gin-starter/internal/service/media"
+	"gin-starter/internal/store"
+	"gin-starter/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testAdminToken = "test-token"
+
+// mediaTestEnv роутер загрузки поверх временных базы и каталога медиа
+type mediaTestEnv struct {
+	router *gin.Engine
+	store  *store.SQLiteStore
+	dir    string
+}
+
+// newMediaTestEnv собирает маршрут загрузки так же, как routes.SetupRoutes
+func newMediaTestEnv(t *testing.T) *mediaTestEnv {
+	t.Helper()
+	dbStore := testutil.NewSQLiteStore(t)
+
+	dir := t.TempDir()
+	storage, err := media.NewLocalStorage(dir, "/static/")
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+
+	env := &mediaTestEnv{router: testutil.NewRouter(dbStore), store: dbStore, dir: dir}
+	env.router.POST("/api/v1/media", middleware.AdminAuthMiddleware(testAdminToken, RenderError),
+		NewMediaHandler(media.NewUploader(storage, media.UploadOptions{})).Upload)
+	return env
+}
+
+// upload отправляет multipart-форму с картинкой и дополнительными полями
+func (e *mediaTestEnv) upload(t *testing.T, token string, fields map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 8, 6))); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "a.png")
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	_, _ = part.Write(img.Bytes())
+	for name, value := range fields {
+		_ = form.WriteField(name, value)
+	}
+	_ = form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/media", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, req)
+	return w
+}
+
+// storedFiles список файлов в каталоге медиа
+func (e *mediaTestEnv) storedFiles(t *testing.T) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(e.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk media dir: %v", err)
+	}
+	return files
+}
+
+// failMediaInserts заставляет базу отклонять новые записи media
+func failMediaInserts(t *testing.T, env *mediaTestEnv) {
+	t.Helper()
+	_, err := env.store.DB.Exec(`CREATE TRIGGER fail_media_insert BEFORE INSERT ON media
+		BEGIN SELECT RAISE(ABORT, 'insert failed'); END`)
+	if err != nil {
+		t.Fatalf("create trigger: %v", err)
+	}
+}
+
+func TestMediaUploadRequiresAuth(t *testing.T) {
+	env := newMediaTestEnv(t)
+
+	for _, token := range []string{"", "wrong"} {
+		w := env.upload(t, token, nil)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("upload with token %q = %d, want 401", token, w.Code)
+		}
+	}
+	if files := env.storedFiles(t); len(files) != 0 {
+		t.Fatalf("unauthorized upload stored %v", files)
+	}
+}
+
+func TestMediaUploadIgnoresOwnerFromForm(t *testing.T) {
+	env := newMediaTestEnv(t)
+
+	w := env.upload(t, testAdminToken, map[string]string{"owner_id": "1", "alt": "picture"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("upload = %d %s, want 201", w.Code, w.Body)
+	}
+
+	records, err := env.store.GetMediaRepo().GetAll()
+	if err != nil || len(records) != 1 {
+		t.Fatalf("media records = %v, %v; want one", records, err)
+	}
+	if records[0].OwnerID != nil {
+		t.Fatalf("OwnerID = %d, want none: owner must not come from the form", *records[0].OwnerID)
+	}
+	if records[0].AltText != "picture" || records[0].Width != 8 || records[0].Height != 6 {
+		t.Fatalf("record = %+v, want alt \"picture\" and 8x6", records[0])
+	}
+	if _, err := os.Stat(filepath.Join(env.dir, filepath.FromSlash(records[0].StorageKey))); err != nil {
+		t.Fatalf("uploaded file: %v", err)
+	}
+}
+
+func TestMediaUploadRemovesFileWhenRecordFails(t *testing.T) {
+	env := newMediaTestEnv(t)
+	failMediaInserts(t, env)
+
+	w := env.upload(t, testAdminToken, nil)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("upload = %d %s, want 500", w.Code, w.Body)
+	}
+	if files := env.storedFiles(t); len(files) != 0 {
+		t.Fatalf("files left after failed insert: %v", files)
+	}
+}
+
+func TestMediaUploadKeepsSharedFileWhenRecordFails(t *testing.T) {
+	env := newMediaTestEnv(t)
+	if w := env.upload(t, testAdminToken, nil); w.Code != http.StatusCreated {
+		t.Fatalf("first upload = %d %s, want 201", w.Code, w.Body)
+	}
+	files := env.storedFiles(t)
+
+	// Повторная загрузка того же содержимого получает тот же ключ, но запись не сохраняется
+	failMediaInserts(t, env)
+	if w := env.upload(t, testAdminToken, nil); w.Code != http.StatusInternalServerError {
+		t.Fatalf("second upload = %d %s, want 500", w.Code, w.Body)
+	}
+
+	if left := env.storedFiles(t); len(left) != 1 || left[0] != files[0] {
+		t.Fatalf("files after failed re-upload = %v, want %v kept for the existing record", left, files)
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"gin-starter/internal/i18n"
+	"gin-starter/internal/siteurl"
+	"gin-starter/templates"
+
+	"github.com/gin-gonic/gin"
+)
+
+// shareImage картинка превью страниц для соцсетей
+const shareImage = "/static/images/face_01.png"
+
+// pageMeta базовые метаданные страницы: заголовок и описание из каталога
+// ("meta.<page>.title", "meta.<page>.description"), канонический адрес,
+// версии на других языках и превью
+func pageMeta(c *gin.Context, page string) templates.PageMeta {
+	ctx := c.Request.Context()
+	return templates.PageMeta{
+		Title:       i18n.T(ctx, "meta."+page+".title"),
+		Description: i18n.T(ctx, "meta."+page+".description"),
+		Canonical:   canonicalURL(c),
+		Path:        c.Request.URL.Path,
+		Image:       shareImage,
+		ImageAlt:    "Gin Starter",
+		Locale:      i18n.OGLocale(i18n.FromContext(ctx)),
+		Alternates:  alternates(c),
+	}
+}
+
+// alternates ссылки hreflang на текущую страницу на всех языках и x-default
+// на язык по умолчанию; параметры запроса берутся те же, что и в canonical
+func alternates(c *gin.Context) []templates.Alternate {
+	resolver := siteURL(c)
+	_, path := i18n.SplitPath(siteurl.CleanPath(c.Request.URL.Path))
+	query := resolver.CanonicalQuery(c.Request.URL.Query())
+
+	link := func(locale string) string {
+		u := resolver.Absolute(c.Request, i18n.LocalizePath(locale, path))
+		if query != "" {
+			u += "?" + query
+		}
+		return u
+	}
+
+	result := make([]templates.Alternate, 0, len(i18n.Locales)+1)
+	for _, locale := range i18n.Locales {
+		result = append(result, templates.Alternate{Lang: locale, URL: link(locale)})
+	}
+	return append(result, templates.Alternate{Lang: "x-default", URL: link(i18n.DefaultLocale)})
+}
+
+// homeMeta метаданные главной: описание сайта для поисковиков в JSON-LD
+func homeMeta(c *gin.Context) templates.PageMeta {
+	meta := pageMeta(c, "home")
+	locale := i18n.FromContext(c.Request.Context())
+	meta.JSONLD = []any{map[string]any{
+		"@context":   "https://schema.org",
+		"@type":      "WebSite",
+		"name":       "Gin Starter",
+		"url":        siteURL(c).Absolute(c.Request, i18n.LocalizePath(locale, "/")),
+		"inLanguage": locale,
+	}}
+	return meta
+}
+
+// aboutMeta метаданные страницы "О нас"
+func aboutMeta(c *gin.Context) templates.PageMeta {
+	return pageMeta(c, "about")
+}
+
+// contactMeta метаданные страницы "Контакты"
+func contactMeta(c *gin.Context) templates.PageMeta {
+	meta := pageMeta(c, "contact")
+	meta.JSONLD = []any{map[string]any{
+		"@context":   "https://schema.org",
+		"@type":      "ContactPage",
+		"name":       meta.Title,
+		"url":        meta.Canonical,
+		"inLanguage": i18n.FromContext(c.Request.Context()),
+	}}
+	return meta
+}
+
+// usersMeta метаданные страницы со списком пользователей. У страниц пагинации
+// свой заголовок, а результаты поиска закрыты от индексации: это бесконечное
+// число почти одинаковых страниц
+func usersMeta(c *gin.Context, list templates.UserList) templates.PageMeta {
+	meta := pageMeta(c, "users")
+	if list.Page > 1 {
+		meta.Title = i18n.T(c.Request.Context(), "meta.users.title_page", meta.Title, list.Page)
+	}
+	if list.Query != "" {
+		meta.Robots = []string{"noindex", "follow"}
+	}
+	return meta
+}
+
+// adminContactMeta метаданные списка сообщений в админке: страница закрыта
+// паролем, но и без этого ей нечего делать в поиске и превью
+func adminContactMeta(c *gin.Context) templates.PageMeta {
+	meta := pageMeta(c, "admin_contact")
+	meta.Canonical = ""
+	meta.Alternates = nil
+	meta.Image = ""
+	meta.Robots = []string{"noindex", "nofollow"}
+	return meta
+}
+
+// errorMeta метаданные страницы ошибки: без canonical, hreflang и превью,
+// закрыта от индексации
+func errorMeta(c *gin.Context, status int) templates.PageMeta {
+	ctx := c.Request.Context()
+	return templates.PageMeta{
+		Title:       templates.ErrorTitle(ctx, status),
+		Description: templates.ErrorText(ctx, status),
+		Path:        c.Request.URL.Path,
+		Locale:      i18n.OGLocale(i18n.FromContext(ctx)),
+		Robots:      []string{"noindex", "nofollow"},
+	}
+}
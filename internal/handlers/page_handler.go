@@ -1,16 +1,28 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
+	"gin-starter/internal/htmx"
+	"gin-starter/internal/i18n"
 	"gin-starter/internal/models"
+	"gin-starter/internal/repository"
 	"gin-starter/internal/store"
 	"gin-starter/templates"
 	"log"
 	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/a-h/templ"
 	"github.com/gin-gonic/gin"
 )
 
+// usersPerPage пользователей на странице списка
+const usersPerPage = 20
+
 // PageHandler структура для обработчиков страниц
 type PageHandler struct{}
 
@@ -21,77 +33,236 @@ func NewPageHandler() *PageHandler {
 
 // Home обработчик для главной страницы
 func (h *PageHandler) Home(c *gin.Context) {
-	canonicalURL := c.Request.URL.Scheme + "://" + c.Request.Host + c.Request.URL.Path
-	menuItems := templates.GetDefaultMenuItems()
 	c.Status(http.StatusOK)
-	if err := templates.IndexPage(canonicalURL, menuItems).Render(c.Request.Context(), c.Writer); err != nil {
+	if err := templates.IndexPage(homeMeta(c)).Render(c.Request.Context(), c.Writer); err != nil {
 		log.Printf("Template render error: %v", err)
-		c.String(http.StatusInternalServerError, "Internal Server Error")
+		RenderError(c, http.StatusInternalServerError, "")
 	}
 }
 
 // About обработчик для страницы "О нас"
 func (h *PageHandler) About(c *gin.Context) {
-	canonicalURL := c.Request.URL.Scheme + "://" + c.Request.Host + c.Request.URL.Path
-	menuItems := templates.GetDefaultMenuItems()
 	c.Status(http.StatusOK)
-	if err := templates.AboutPage(canonicalURL, menuItems).Render(c.Request.Context(), c.Writer); err != nil {
+	if err := templates.AboutPage(aboutMeta(c)).Render(c.Request.Context(), c.Writer); err != nil {
 		log.Printf("Template render error: %v", err)
-		c.String(http.StatusInternalServerError, "Internal Server Error")
+		RenderError(c, http.StatusInternalServerError, "")
 	}
 }
 
-// Contact обработчик для страницы "Контакты"
-func (h *PageHandler) Contact(c *gin.Context) {
-	canonicalURL := c.Request.URL.Scheme + "://" + c.Request.Host + c.Request.URL.Path
-	menuItems := templates.GetDefaultMenuItems()
-	c.Status(http.StatusOK)
-	if err := templates.ContactPage(canonicalURL, menuItems).Render(c.Request.Context(), c.Writer); err != nil {
-		log.Printf("Template render error: %v", err)
-		c.String(http.StatusInternalServerError, "Internal Server Error")
+// Users обработчик для страницы со списком пользователей: поиск (?q=) и
+// пагинация (?page=) на сервере, страница работает и без JavaScript. На запрос
+// фрагмента (htmx) отдается только список
+func (h *PageHandler) Users(c *gin.Context) {
+	// Получаем доступ к базе данных из контекста
+	dbStore, exists := c.Get("dbStore")
+	if !exists {
+		log.Println("Database connection not found in context")
+		RenderError(c, http.StatusServiceUnavailable, i18n.T(c.Request.Context(), "error.db_unavailable"))
+		return
 	}
+
+	// Приводим к нужному типу
+	store := dbStore.(store.Store)
+
+	// Номер страницы: без параметра - первая, мусор и номера за концом списка - 404,
+	// чтобы поисковики не индексировали пустые дубли
+	page := 1
+	if value := c.Query("page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			NotFoundHandler(c)
+			return
+		}
+		page = parsed
+	}
+
+	list, err := loadUserList(store, c.Query("q"), page)
+	if err != nil {
+		log.Printf("Error getting users: %v", err)
+		RenderError(c, http.StatusInternalServerError, i18n.T(c.Request.Context(), "error.users_get_failed"))
+		return
+	}
+	if page > 1 && page > list.TotalPages {
+		NotFoundHandler(c)
+		return
+	}
+
+	h.renderUsers(c, http.StatusOK, list)
 }
 
-// Users обработчик для страницы со списком пользователей
-func (h *PageHandler) Users(c *gin.Context) {
+// CreateUserForm обработчик формы добавления пользователя (POST /users).
+// htmx получает новую строку таблицы и обновленный итог (out-of-band), ошибка
+// проверки уходит в #user-form-error. Без JS - редирект на список или повторный
+// показ страницы с ошибкой в форме
+func (h *PageHandler) CreateUserForm(c *gin.Context) {
 	// Получаем доступ к базе данных из контекста
 	dbStore, exists := c.Get("dbStore")
 	if !exists {
 		log.Println("Database connection not found in context")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		RenderError(c, http.StatusServiceUnavailable, i18n.T(c.Request.Context(), "error.db_unavailable"))
 		return
 	}
 
 	// Приводим к нужному типу
 	store := dbStore.(store.Store)
 
-	// Получаем всех пользователей из базы данных
-	_, err := store.GetUserRepo().GetAll()
+	ctx := c.Request.Context()
+	form := templates.UserForm{
+		Name:  strings.TrimSpace(c.PostForm("name")),
+		Email: strings.TrimSpace(c.PostForm("email")),
+	}
+	form.Error = validateUserForm(ctx, store, form)
+
+	if form.Error != "" {
+		if htmx.IsPartial(c.Request) {
+			// htmx по умолчанию не вставляет ответы 4xx, поэтому ошибка проверки
+			// приходит с 200 и перенаправляется в блок ошибки формы
+			htmx.Retarget(c.Writer, "#user-form-error")
+			htmx.Reswap(c.Writer, "innerHTML")
+			h.render(c, http.StatusOK, templates.UserFormError(form.Error))
+			return
+		}
+
+		list, err := loadUserList(store, "", 1)
+		if err != nil {
+			log.Printf("Error getting users: %v", err)
+			RenderError(c, http.StatusInternalServerError, i18n.T(ctx, "error.users_get_failed"))
+			return
+		}
+		list.Form = form
+		h.renderUsers(c, http.StatusUnprocessableEntity, list)
+		return
+	}
+
+	user := models.User{Name: form.Name, Email: form.Email}
+	if err := store.GetUserRepo().Create(&user); err != nil {
+		log.Printf("Error creating user: %v", err)
+		RenderError(c, http.StatusInternalServerError, i18n.T(ctx, "error.user_create_failed"))
+		return
+	}
+
+	if !htmx.IsPartial(c.Request) {
+		c.Redirect(http.StatusSeeOther, i18n.Path(ctx, "/users"))
+		return
+	}
+	htmx.Trigger(c.Writer, "user-created")
+	h.render(c, http.StatusOK, htmx.Fragments(templates.UserRow(&user), usersSummary(c, store)))
+}
+
+// DeleteUserForm обработчик формы удаления пользователя (POST /users/:id/delete).
+// htmx получает пустую строку (строка таблицы удаляется) и обновленный итог
+func (h *PageHandler) DeleteUserForm(c *gin.Context) {
+	// Получаем доступ к базе данных из контекста
+	dbStore, exists := c.Get("dbStore")
+	if !exists {
+		log.Println("Database connection not found in context")
+		RenderError(c, http.StatusServiceUnavailable, i18n.T(c.Request.Context(), "error.db_unavailable"))
+		return
+	}
+
+	// Приводим к нужному типу
+	store := dbStore.(store.Store)
+
+	ctx := c.Request.Context()
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
-		log.Printf("Error getting users: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get users"})
+		RenderError(c, http.StatusBadRequest, i18n.T(ctx, "error.invalid_user_id"))
 		return
 	}
 
-	// Формируем canonical URL
-	canonicalURL := c.Request.URL.Scheme + "://" + c.Request.Host + c.Request.URL.Path
-	menuItems := templates.GetDefaultMenuItems()
+	if err := store.GetUserRepo().Delete(uint(id)); err != nil {
+		log.Printf("Error deleting user: %v", err)
+		RenderError(c, http.StatusInternalServerError, i18n.T(ctx, "error.user_delete_failed"))
+		return
+	}
 
-	// Отображаем страницу с пользователями
-	c.Status(http.StatusOK)
-	if err := templates.UsersPage(canonicalURL, menuItems).Render(c.Request.Context(), c.Writer); err != nil {
+	if !htmx.IsPartial(c.Request) {
+		c.Redirect(http.StatusSeeOther, i18n.Path(ctx, "/users"))
+		return
+	}
+	htmx.Trigger(c.Writer, "user-deleted")
+	h.render(c, http.StatusOK, htmx.Fragments(nil, usersSummary(c, store)))
+}
+
+// renderUsers отдает страницу пользователей или, на запрос htmx, только список
+func (h *PageHandler) renderUsers(c *gin.Context, status int, list templates.UserList) {
+	page := templates.UsersPage(usersMeta(c, list), list)
+	if err := htmx.Render(c.Writer, c.Request, status, page, templates.UsersList(list)); err != nil {
+		log.Printf("Template render error: %v", err)
+	}
+}
+
+// render отдает HTML-фрагмент
+func (h *PageHandler) render(c *gin.Context, status int, fragment templ.Component) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(status)
+	if err := fragment.Render(c.Request.Context(), c.Writer); err != nil {
 		log.Printf("Template render error: %v", err)
-		c.String(http.StatusInternalServerError, "Internal Server Error")
 	}
 }
 
+// loadUserList страница списка пользователей по строке поиска
+func loadUserList(s store.Store, query string, page int) (templates.UserList, error) {
+	query = strings.TrimSpace(query)
+	if runes := []rune(query); len(runes) > templates.MaxUserQueryLength {
+		query = string(runes[:templates.MaxUserQueryLength])
+	}
+
+	users, total, err := s.GetUserRepo().List(repository.UserListOptions{
+		Query:  query,
+		Limit:  usersPerPage,
+		Offset: (page - 1) * usersPerPage,
+	})
+	if err != nil {
+		return templates.UserList{}, err
+	}
+
+	return templates.UserList{
+		Users:      users,
+		Query:      query,
+		Page:       page,
+		TotalPages: (total + usersPerPage - 1) / usersPerPage,
+		Total:      total,
+	}, nil
+}
+
+// usersSummary итог списка для out-of-band обновления: поиск берется из адреса
+// страницы, с которой пришел запрос (HX-Current-URL)
+func usersSummary(c *gin.Context, s store.Store) templ.Component {
+	query := ""
+	if current := htmx.CurrentURL(c.Request); current != nil {
+		query = current.Query().Get("q")
+	}
+	list, err := loadUserList(s, query, 1)
+	if err != nil {
+		log.Printf("Error counting users: %v", err)
+		return templ.NopComponent
+	}
+	return templates.UsersSummary(list.Total, list.Query, true)
+}
+
+// validateUserForm проверяет форму добавления; пусто - ошибок нет
+func validateUserForm(ctx context.Context, s store.Store, form templates.UserForm) string {
+	if form.Name == "" || utf8.RuneCountInString(form.Name) > templates.MaxUserNameLength {
+		return i18n.T(ctx, "users.name_required")
+	}
+	address, err := mail.ParseAddress(form.Email)
+	if err != nil || address.Address != form.Email {
+		return i18n.T(ctx, "users.email_invalid")
+	}
+	if existing, err := s.GetUserRepo().GetByEmail(form.Email); err == nil && existing != nil {
+		return i18n.T(ctx, "users.email_taken")
+	}
+	return ""
+}
+
 // GetUsers обработчик для получения списка пользователей
 func (h *PageHandler) GetUsers(c *gin.Context) {
 	// Получаем доступ к базе данных из контекста
 	dbStore, exists := c.Get("dbStore")
 	if !exists {
 		log.Println("Database connection not found in context")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		RenderError(c, http.StatusServiceUnavailable, i18n.T(c.Request.Context(), "error.db_unavailable"))
 		return
 	}
 
@@ -102,7 +273,7 @@ func (h *PageHandler) GetUsers(c *gin.Context) {
 	users, err := store.GetUserRepo().GetAll()
 	if err != nil {
 		log.Printf("Error getting users: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get users"})
+		RenderError(c, http.StatusInternalServerError, i18n.T(c.Request.Context(), "error.users_get_failed"))
 		return
 	}
 
@@ -115,7 +286,7 @@ func (h *PageHandler) CreateTestUsers(c *gin.Context) {
 	dbStore, exists := c.Get("dbStore")
 	if !exists {
 		log.Println("Database connection not found in context")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		RenderError(c, http.StatusServiceUnavailable, i18n.T(c.Request.Context(), "error.db_unavailable"))
 		return
 	}
 
@@ -145,7 +316,7 @@ func (h *PageHandler) CreateTestUsers(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Test users created successfully", "count": createdCount})
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c.Request.Context(), "message.test_users_created", createdCount), "count": createdCount})
 }
 
 // CreateUser обработчик для создания нового пользователя
@@ -154,7 +325,7 @@ func (h *PageHandler) CreateUser(c *gin.Context) {
 	dbStore, exists := c.Get("dbStore")
 	if !exists {
 		log.Println("Database connection not found in context")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		RenderError(c, http.StatusServiceUnavailable, i18n.T(c.Request.Context(), "error.db_unavailable"))
 		return
 	}
 
@@ -164,14 +335,14 @@ func (h *PageHandler) CreateUser(c *gin.Context) {
 	// Получаем данные из формы
 	var user models.User
 	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RenderError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Создаем пользователя в базе данных
 	if err := store.GetUserRepo().Create(&user); err != nil {
 		log.Printf("Error creating user: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		RenderError(c, http.StatusInternalServerError, i18n.T(c.Request.Context(), "error.user_create_failed"))
 		return
 	}
 
@@ -187,7 +358,7 @@ func (h *PageHandler) DeleteUser(c *gin.Context) {
 	dbStore, exists := c.Get("dbStore")
 	if !exists {
 		log.Println("Database connection not found in context")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		RenderError(c, http.StatusServiceUnavailable, i18n.T(c.Request.Context(), "error.db_unavailable"))
 		return
 	}
 
@@ -199,16 +370,16 @@ func (h *PageHandler) DeleteUser(c *gin.Context) {
 	_, err := fmt.Sscanf(userID, "%d", &id)
 	if err != nil {
 		log.Printf("Error parsing user ID: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		RenderError(c, http.StatusBadRequest, i18n.T(c.Request.Context(), "error.invalid_user_id"))
 		return
 	}
 
 	// Удаляем пользователя из базы данных
 	if err := store.GetUserRepo().Delete(id); err != nil {
 		log.Printf("Error deleting user: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		RenderError(c, http.StatusInternalServerError, i18n.T(c.Request.Context(), "error.user_delete_failed"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c.Request.Context(), "message.user_deleted")})
 }
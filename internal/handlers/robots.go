@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+
+	"gin-starter/internal/service/robots"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RobotsHandler отдает robots.txt по правилам из конфига
+type RobotsHandler struct {
+	groups    []robots.Group
+	indexable bool // только production открыт для индексации
+}
+
+// NewRobotsHandler создает новый экземпляр RobotsHandler
+func NewRobotsHandler(groups []robots.Group, indexable bool) *RobotsHandler {
+	return &RobotsHandler{groups: groups, indexable: indexable}
+}
+
+// Robots обработчик для /robots.txt
+func (h *RobotsHandler) Robots(c *gin.Context) {
+	sitemapURL := requestBaseURL(c) + "/sitemap.xml"
+
+	var buf bytes.Buffer
+	if err := robots.Write(&buf, h.groups, sitemapURL, h.indexable); err != nil {
+		log.Printf("robots.txt render error: %v", err)
+		RenderError(c, http.StatusInternalServerError, "")
+		return
+	}
+
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", buf.Bytes())
+}
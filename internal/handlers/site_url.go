@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"gin-starter/internal/siteurl"
+
+	"github.com/gin-gonic/gin"
+)
+
+// siteURL возвращает резолвер публичных адресов из контекста (middleware.SiteURLMiddleware)
+func siteURL(c *gin.Context) *siteurl.Resolver {
+	if value, exists := c.Get("siteURL"); exists {
+		if resolver, ok := value.(*siteurl.Resolver); ok {
+			return resolver
+		}
+	}
+	return siteurl.Default
+}
+
+// requestBaseURL публичный адрес сайта без "/" в конце
+func requestBaseURL(c *gin.Context) string {
+	return siteURL(c).BaseURL(c.Request)
+}
+
+// canonicalURL абсолютный канонический адрес текущей страницы
+func canonicalURL(c *gin.Context) string {
+	return siteURL(c).Canonical(c.Request)
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gin-starter/internal/i18n"
+	"gin-starter/internal/service/sitemap"
+	"gin-starter/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SitemapRoute путь частей карты сайта, когда ссылок больше sitemap.MaxURLsPerFile
+const SitemapRoute = "/sitemaps"
+
+// SitemapHandler отдает /sitemap.xml, собранный из маршрутов страниц и динамических источников
+type SitemapHandler struct {
+	sitemap *sitemap.Sitemap
+}
+
+// NewSitemapHandler создает новый экземпляр SitemapHandler
+func NewSitemapHandler(sm *sitemap.Sitemap) *SitemapHandler {
+	return &SitemapHandler{sitemap: sm}
+}
+
+// AddPage регистрирует страницу в карте сайта; вызывается при описании маршрутов
+func (h *SitemapHandler) AddPage(path string, page sitemap.Page) {
+	h.sitemap.AddPage(path, page)
+}
+
+// Sitemap отдает карту сайта или, если ссылок больше лимита протокола, индекс ее частей
+func (h *SitemapHandler) Sitemap(c *gin.Context) {
+	entries := h.sitemap.Entries(c.Request.Context())
+	chunks := sitemap.Chunks(entries)
+	baseURL := requestBaseURL(c)
+
+	if len(chunks) == 1 {
+		h.writeXML(c, sitemap.LastModified(entries), func(w io.Writer) error {
+			return sitemap.WriteURLSet(w, baseURL, entries)
+		})
+		return
+	}
+
+	index := make([]sitemap.IndexEntry, len(chunks))
+	for i, chunk := range chunks {
+		index[i] = sitemap.IndexEntry{
+			Loc:     baseURL + SitemapRoute + "/" + strconv.Itoa(i+1) + ".xml",
+			LastMod: sitemap.LastModified(chunk),
+		}
+	}
+	h.writeXML(c, sitemap.LastModified(entries), func(w io.Writer) error {
+		return sitemap.WriteIndex(w, index)
+	})
+}
+
+// Part отдает часть карты сайта из индекса: /sitemaps/1.xml
+func (h *SitemapHandler) Part(c *gin.Context) {
+	number, err := strconv.Atoi(strings.TrimSuffix(c.Param("file"), ".xml"))
+	chunks := sitemap.Chunks(h.sitemap.Entries(c.Request.Context()))
+	if err != nil || number < 1 || number > len(chunks) || len(chunks) == 1 {
+		NotFoundHandler(c)
+		return
+	}
+
+	chunk := chunks[number-1]
+	baseURL := requestBaseURL(c)
+	h.writeXML(c, sitemap.LastModified(chunk), func(w io.Writer) error {
+		return sitemap.WriteURLSet(w, baseURL, chunk)
+	})
+}
+
+// writeXML отдает XML с Last-Modified и ответом 304 на условный запрос.
+// Карта сайта на десятки тысяч ссылок хорошо сжимается, поэтому клиентам
+// с Accept-Encoding: gzip она уходит сжатой
+func (h *SitemapHandler) writeXML(c *gin.Context, lastMod time.Time, write func(w io.Writer) error) {
+	c.Header("Vary", "Accept-Encoding")
+	if !lastMod.IsZero() {
+		lastMod = lastMod.UTC().Truncate(time.Second)
+		c.Header("Last-Modified", lastMod.Format(http.TimeFormat))
+		if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !lastMod.After(since) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if acceptsGzip(c.GetHeader("Accept-Encoding")) {
+		zw := gzip.NewWriter(&buf)
+		if err = write(zw); err == nil {
+			err = zw.Close()
+		}
+		c.Header("Content-Encoding", "gzip")
+	} else {
+		err = write(&buf)
+	}
+	if err != nil {
+		log.Printf("Sitemap render error: %v", err)
+		c.Header("Content-Encoding", "")
+		RenderError(c, http.StatusInternalServerError, "")
+		return
+	}
+
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", buf.Bytes())
+}
+
+// acceptsGzip сообщает, принимает ли клиент gzip (с учетом "gzip;q=0")
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		q, found := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !found {
+			return true
+		}
+		value, err := strconv.ParseFloat(q, 64)
+		return err == nil && value > 0
+	}
+	return false
+}
+
+// UsersSitemapSource добавляет страницу /users на всех языках с lastmod по последнему
+// измененному пользователю. Без базы данных страница не работает, поэтому в карту
+// сайта она попадает только через этот источник, а он подключается только с базой
+func UsersSitemapSource(s store.Store) sitemap.Source {
+	return sitemap.SourceFunc(func(ctx context.Context) ([]sitemap.Entry, error) {
+		repo := s.GetUserRepo()
+		if repo == nil {
+			return nil, nil
+		}
+		lastMod, err := repo.LastModified()
+		if err != nil {
+			return nil, err
+		}
+		entries := make([]sitemap.Entry, 0, len(i18n.Locales))
+		for _, locale := range i18n.Locales {
+			entries = append(entries, sitemap.Entry{
+				Loc:        i18n.LocalizePath(locale, "/users"),
+				LastMod:    lastMod, // пусто, пока пользователей нет: lastmod не указывается
+				ChangeFreq: sitemap.Daily,
+				Priority:   0.6,
+			})
+		}
+		return entries, nil
+	})
+}
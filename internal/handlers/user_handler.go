@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"fmt"
+	"gin-starter/internal/i18n"
 	"gin-starter/internal/models"
 	"gin-starter/internal/store"
 	"log"
@@ -24,7 +25,7 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 	dbStore, exists := c.Get("dbStore")
 	if !exists {
 		log.Println("Database connection not found in context")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		RenderError(c, http.StatusServiceUnavailable, i18n.T(c.Request.Context(), "error.db_unavailable"))
 		return
 	}
 
@@ -35,7 +36,7 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 	users, err := store.GetUserRepo().GetAll()
 	if err != nil {
 		log.Printf("Error getting users: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get users"})
+		RenderError(c, http.StatusInternalServerError, i18n.T(c.Request.Context(), "error.users_get_failed"))
 		return
 	}
 
@@ -48,7 +49,7 @@ func (h *UserHandler) CreateTestUsers(c *gin.Context) {
 	dbStore, exists := c.Get("dbStore")
 	if !exists {
 		log.Println("Database connection not found in context")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		RenderError(c, http.StatusServiceUnavailable, i18n.T(c.Request.Context(), "error.db_unavailable"))
 		return
 	}
 
@@ -78,7 +79,7 @@ func (h *UserHandler) CreateTestUsers(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Test users created successfully", "count": createdCount})
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c.Request.Context(), "message.test_users_created", createdCount), "count": createdCount})
 }
 
 // CreateUser обработчик для создания нового пользователя
@@ -87,7 +88,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 	dbStore, exists := c.Get("dbStore")
 	if !exists {
 		log.Println("Database connection not found in context")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		RenderError(c, http.StatusServiceUnavailable, i18n.T(c.Request.Context(), "error.db_unavailable"))
 		return
 	}
 
@@ -97,14 +98,14 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 	// Получаем данные из формы
 	var user models.User
 	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RenderError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Создаем пользователя в базе данных
 	if err := store.GetUserRepo().Create(&user); err != nil {
 		log.Printf("Error creating user: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		RenderError(c, http.StatusInternalServerError, i18n.T(c.Request.Context(), "error.user_create_failed"))
 		return
 	}
 
@@ -120,7 +121,7 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	dbStore, exists := c.Get("dbStore")
 	if !exists {
 		log.Println("Database connection not found in context")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		RenderError(c, http.StatusServiceUnavailable, i18n.T(c.Request.Context(), "error.db_unavailable"))
 		return
 	}
 
@@ -132,16 +133,16 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	_, err := fmt.Sscanf(userID, "%d", &id)
 	if err != nil {
 		log.Printf("Error parsing user ID: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		RenderError(c, http.StatusBadRequest, i18n.T(c.Request.Context(), "error.invalid_user_id"))
 		return
 	}
 
 	// Удаляем пользователя из базы данных
 	if err := store.GetUserRepo().Delete(id); err != nil {
 		log.Printf("Error deleting user: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		RenderError(c, http.StatusInternalServerError, i18n.T(c.Request.Context(), "error.user_delete_failed"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c.Request.Context(), "message.user_deleted")})
 }
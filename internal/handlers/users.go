@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"gin-starter/internal/i18n"
 	"gin-starter/internal/models"
 	"gin-starter/internal/store"
 	"log"
@@ -15,7 +16,7 @@ func CreateUserHandler(c *gin.Context) {
 	dbStore, exists := c.Get("dbStore")
 	if !exists {
 		log.Println("Database connection not found in context")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		RenderError(c, http.StatusServiceUnavailable, i18n.T(c.Request.Context(), "error.db_unavailable"))
 		return
 	}
 
@@ -25,16 +26,16 @@ func CreateUserHandler(c *gin.Context) {
 	// Получаем данные из формы
 	var user models.User
 	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RenderError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Создаем пользователя в базе данных
 	if err := store.GetUserRepo().Create(&user); err != nil {
 		log.Printf("Error creating user: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		RenderError(c, http.StatusInternalServerError, i18n.T(c.Request.Context(), "error.user_create_failed"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User created successfully", "user": user})
+	c.JSON(http.StatusOK, gin.H{"message": i18n.T(c.Request.Context(), "message.user_created"), "user": user})
 }
@@ -0,0 +1,141 @@
+package htmx
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/a-h/templ"
+)
+
+// Заголовки запроса htmx и X-Partial для своих клиентов (fetch) без htmx
+const (
+	HeaderRequest        = "HX-Request"
+	HeaderBoosted        = "HX-Boosted"
+	HeaderHistoryRestore = "HX-History-Restore-Request"
+	HeaderCurrentURL     = "HX-Current-URL"
+	HeaderTarget         = "HX-Target"
+	HeaderPartial        = "X-Partial"
+)
+
+// Заголовки ответа, которыми сервер управляет htmx
+const (
+	HeaderTrigger  = "HX-Trigger"
+	HeaderRedirect = "HX-Redirect"
+	HeaderRefresh  = "HX-Refresh"
+	HeaderPushURL  = "HX-Push-Url"
+	HeaderRetarget = "HX-Retarget"
+	HeaderReswap   = "HX-Reswap"
+)
+
+// IsPartial ждет ли клиент фрагмент страницы вместо целой страницы.
+// Boosted-запросы и восстановление истории htmx ждут страницу целиком:
+// их ответ заменяет весь <body>
+func IsPartial(r *http.Request) bool {
+	if r.Header.Get(HeaderPartial) != "" {
+		return true
+	}
+	return r.Header.Get(HeaderRequest) == "true" &&
+		r.Header.Get(HeaderBoosted) != "true" &&
+		r.Header.Get(HeaderHistoryRestore) != "true"
+}
+
+// Target id элемента, в который htmx вставит ответ (без "#"); пусто, если не задан
+func Target(r *http.Request) string {
+	return r.Header.Get(HeaderTarget)
+}
+
+// CurrentURL адрес страницы, с которой отправлен запрос htmx; nil, если его нет.
+// Нужен, например, чтобы после удаления пересчитать список с тем же поиском
+func CurrentURL(r *http.Request) *url.URL {
+	value := r.Header.Get(HeaderCurrentURL)
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil
+	}
+	return u
+}
+
+// Vary отмечает, что ответ зависит от заголовков фрагментов: кэш не должен
+// отдать фрагмент на обычный запрос страницы и наоборот
+func Vary(w http.ResponseWriter) {
+	w.Header().Add("Vary", HeaderRequest+", "+HeaderPartial)
+}
+
+// Trigger просит htmx вызвать события на клиенте после ответа
+// ("user-created"); слушать их можно через x-on:user-created.window в Alpine
+func Trigger(w http.ResponseWriter, events ...string) {
+	w.Header().Set(HeaderTrigger, strings.Join(events, ", "))
+}
+
+// TriggerDetail вызывает события с данными: detail попадает в event.detail
+func TriggerDetail(w http.ResponseWriter, events map[string]any) error {
+	data, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	w.Header().Set(HeaderTrigger, string(data))
+	return nil
+}
+
+// Redirect полный переход на адрес на стороне клиента. Обычный 3xx htmx
+// выполнил бы сам и вставил результат во фрагмент
+func Redirect(w http.ResponseWriter, target string) {
+	w.Header().Set(HeaderRedirect, target)
+}
+
+// Refresh перезагрузка страницы на клиенте
+func Refresh(w http.ResponseWriter) {
+	w.Header().Set(HeaderRefresh, "true")
+}
+
+// PushURL адрес для истории браузера после вставки фрагмента
+func PushURL(w http.ResponseWriter, target string) {
+	w.Header().Set(HeaderPushURL, target)
+}
+
+// Retarget меняет элемент, в который вставляется ответ (CSS-селектор)
+func Retarget(w http.ResponseWriter, selector string) {
+	w.Header().Set(HeaderRetarget, selector)
+}
+
+// Reswap меняет способ вставки ответа (innerHTML, outerHTML, afterbegin...)
+func Reswap(w http.ResponseWriter, swap string) {
+	w.Header().Set(HeaderReswap, swap)
+}
+
+// Fragments склеивает основной фрагмент и out-of-band фрагменты
+// (элементы с hx-swap-oob, которые htmx вставит по их id) в один ответ
+func Fragments(main templ.Component, oob ...templ.Component) templ.Component {
+	return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		if main != nil {
+			if err := main.Render(ctx, w); err != nil {
+				return err
+			}
+		}
+		for _, component := range oob {
+			if err := component.Render(ctx, w); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Render отдает фрагмент на запрос фрагмента и страницу целиком на обычный запрос
+func Render(w http.ResponseWriter, r *http.Request, status int, page, fragment templ.Component) error {
+	Vary(w)
+	component := page
+	if IsPartial(r) {
+		component = fragment
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	return component.Render(r.Context(), w)
+}
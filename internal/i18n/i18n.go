@@ -0,0 +1,163 @@
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultLocale язык сайта по умолчанию: его страницы живут без префикса в URL
+const DefaultLocale = "ru"
+
+// Locales поддерживаемые языки; первым идет язык по умолчанию
+var Locales = []string{DefaultLocale, "en"}
+
+//go:embed locales/*.json
+var catalogFS embed.FS
+
+// forms формы сообщения по категориям множественного числа (one, few, many, other).
+// Сообщение без форм хранится как "other"
+type forms map[string]string
+
+// catalogs сообщения по языкам; загружаются из встроенных файлов при старте
+var catalogs = mustLoadCatalogs()
+
+type localeKey struct{}
+
+// WithLocale возвращает контекст с языком запроса; T и шаблоны берут язык из него
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// FromContext язык из контекста; без языка - DefaultLocale
+func FromContext(ctx context.Context) string {
+	if ctx != nil {
+		if locale, ok := ctx.Value(localeKey{}).(string); ok && locale != "" {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+// T переводит сообщение на язык из контекста. Пригоден для шаблонов templ:
+//
+//	{ i18n.T(ctx, "users.title") }
+//
+// Аргументы подставляются через fmt.Sprintf. Если у сообщения есть формы
+// множественного числа, форма выбирается по первому целочисленному аргументу:
+// T(ctx, "users.count", 5) -> "5 пользователей"
+func T(ctx context.Context, key string, args ...any) string {
+	return Translate(FromContext(ctx), key, args...)
+}
+
+// Translate переводит сообщение на указанный язык. Нет перевода - берется
+// язык по умолчанию, нет и его - возвращается сам ключ, чтобы пропуск было видно на странице
+func Translate(locale, key string, args ...any) string {
+	message, ok := catalogs[locale][key]
+	if !ok {
+		locale = DefaultLocale
+		message, ok = catalogs[DefaultLocale][key]
+	}
+	if !ok {
+		return key
+	}
+
+	text := message["other"]
+	if len(message) > 1 {
+		if n, ok := firstCount(args); ok {
+			if form, ok := message[pluralCategory(locale, n)]; ok {
+				text = form
+			}
+		}
+	}
+
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// firstCount первый целочисленный аргумент - число для выбора формы
+func firstCount(args []any) (int64, bool) {
+	for _, arg := range args {
+		switch n := arg.(type) {
+		case int:
+			return int64(n), true
+		case int64:
+			return n, true
+		case int32:
+			return int64(n), true
+		case uint:
+			return int64(n), true
+		case uint64:
+			return int64(n), true
+		case uint32:
+			return int64(n), true
+		}
+	}
+	return 0, false
+}
+
+// mustLoadCatalogs читает встроенные каталоги. Каталоги зашиты в бинарник,
+// поэтому ошибка в них - ошибка сборки, и сервер не должен стартовать
+func mustLoadCatalogs() map[string]map[string]forms {
+	loaded, err := loadCatalogs()
+	if err != nil {
+		panic(fmt.Sprintf("i18n: %v", err))
+	}
+	return loaded
+}
+
+// loadCatalogs разбирает locales/<язык>.json. Значение - строка или объект
+// с формами множественного числа. Каждый ключ языка по умолчанию должен быть
+// переведен на остальные языки, иначе часть страницы молча останется на русском
+func loadCatalogs() (map[string]map[string]forms, error) {
+	loaded := make(map[string]map[string]forms, len(Locales))
+	for _, locale := range Locales {
+		data, err := catalogFS.ReadFile("locales/" + locale + ".json")
+		if err != nil {
+			return nil, fmt.Errorf("catalog %s: %w", locale, err)
+		}
+
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("catalog %s: %w", locale, err)
+		}
+
+		catalog := make(map[string]forms, len(raw))
+		for key, value := range raw {
+			var text string
+			if err := json.Unmarshal(value, &text); err == nil {
+				catalog[key] = forms{"other": text}
+				continue
+			}
+			var plural forms
+			if err := json.Unmarshal(value, &plural); err != nil {
+				return nil, fmt.Errorf("catalog %s, key %q: expected string or plural forms", locale, key)
+			}
+			if _, ok := plural["other"]; !ok {
+				return nil, fmt.Errorf("catalog %s, key %q: plural forms must include \"other\"", locale, key)
+			}
+			catalog[key] = plural
+		}
+		loaded[locale] = catalog
+	}
+
+	for _, locale := range Locales[1:] {
+		var missing []string
+		for key := range loaded[DefaultLocale] {
+			if _, ok := loaded[locale][key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			return nil, fmt.Errorf("catalog %s: missing keys %s", locale, strings.Join(missing, ", "))
+		}
+	}
+
+	return loaded, nil
+}
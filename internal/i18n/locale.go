@@ -0,0 +1,120 @@
+package i18n
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CookieName cookie с выбранным языком; ставится при открытии страницы на этом языке
+const CookieName = "lang"
+
+// ogLocales локали Open Graph для поддерживаемых языков
+var ogLocales = map[string]string{
+	"ru": "ru_RU",
+	"en": "en_US",
+}
+
+// Supported сообщает, поддерживается ли язык
+func Supported(locale string) bool {
+	for _, supported := range Locales {
+		if supported == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// OGLocale локаль для og:locale: ru -> ru_RU
+func OGLocale(locale string) string {
+	if og, ok := ogLocales[locale]; ok {
+		return og
+	}
+	return ogLocales[DefaultLocale]
+}
+
+// SplitPath отделяет языковой префикс от пути: "/en/about" -> ("en", "/about").
+// Путь без префикса относится к языку по умолчанию
+func SplitPath(p string) (locale, rest string) {
+	for _, candidate := range Locales[1:] {
+		prefix := "/" + candidate
+		if p == prefix {
+			return candidate, "/"
+		}
+		if strings.HasPrefix(p, prefix+"/") {
+			return candidate, p[len(prefix):]
+		}
+	}
+	return DefaultLocale, p
+}
+
+// LocalizePath путь страницы на языке: язык по умолчанию без префикса,
+// остальные с префиксом ("/about" -> "/en/about", "/" -> "/en")
+func LocalizePath(locale, p string) string {
+	if locale == DefaultLocale || !Supported(locale) {
+		return p
+	}
+	if p == "/" || p == "" {
+		return "/" + locale
+	}
+	return "/" + locale + p
+}
+
+// Path путь страницы на языке из контекста; для ссылок в шаблонах
+func Path(ctx context.Context, p string) string {
+	return LocalizePath(FromContext(ctx), p)
+}
+
+// Detect определяет язык запроса: префикс в URL, затем cookie, затем
+// Accept-Language, иначе язык по умолчанию. Страницы сайта язык не угадывают -
+// он задан их адресом (см. middleware.PageLocaleMiddleware), а Detect нужен
+// для API и ответов без маршрута
+func Detect(r *http.Request) string {
+	if locale, _ := SplitPath(r.URL.Path); locale != DefaultLocale {
+		return locale
+	}
+	if cookie, err := r.Cookie(CookieName); err == nil && Supported(cookie.Value) {
+		return cookie.Value
+	}
+	if locale := MatchAcceptLanguage(r.Header.Get("Accept-Language")); locale != "" {
+		return locale
+	}
+	return DefaultLocale
+}
+
+// MatchAcceptLanguage выбирает поддерживаемый язык из Accept-Language с учетом
+// q-весов ("en-US,en;q=0.9,ru;q=0.8" -> en). Пусто, если ничего не подошло
+func MatchAcceptLanguage(header string) string {
+	type candidate struct {
+		locale string
+		q      float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if !Supported(base) {
+			continue
+		}
+		q := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{locale: base, q: q})
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].locale
+}
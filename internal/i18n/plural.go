@@ -0,0 +1,30 @@
+package i18n
+
+// pluralCategory категория множественного числа для целого n по правилам CLDR:
+// в русском one (1, 21, 101), few (2-4, 22-24), many (0, 5-20, 25); в английском
+// one (1) и other. Для неизвестного языка - other
+func pluralCategory(locale string, n int64) string {
+	if n < 0 {
+		n = -n
+	}
+
+	switch locale {
+	case "ru":
+		mod10, mod100 := n%10, n%100
+		switch {
+		case mod10 == 1 && mod100 != 11:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	case "en":
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	default:
+		return "other"
+	}
+}
@@ -1,12 +1,69 @@
 package middleware
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"syscall"
 	"time"
 
+	"gin-starter/internal/i18n"
+	"gin-starter/internal/siteurl"
+
 	"github.com/gin-gonic/gin"
 )
 
+// ErrorFunc отвечает клиенту ошибкой (страница или problem+json, см. handlers.RenderError)
+// и прерывает обработку запроса
+type ErrorFunc func(c *gin.Context, status int, detail string)
+
+// RequestIDHeader заголовок с id запроса: приходит от прокси или создается
+// здесь и возвращается клиенту
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength предельная длина id запроса, принятого от клиента
+const maxRequestIDLength = 64
+
+// RequestIDMiddleware кладет в контекст id запроса ("requestID") и отдает его
+// в заголовке ответа. id от прокси принимается, если он короткий и без
+// посторонних символов: он попадает в журнал и на страницу ошибки
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set("requestID", id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// validRequestID состоит ли id только из букв, цифр, "-", "_" и "."
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID случайный id запроса из 16 байт в hex
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
 // LoggerMiddleware логирует каждый HTTP запрос
 func LoggerMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -16,16 +73,51 @@ func LoggerMiddleware() gin.HandlerFunc {
 
 		duration := time.Since(start)
 
-		log.Printf("[%s] %s %s %d %v",
+		log.Printf("[%s] %s %s %d %v %s",
 			c.ClientIP(),
 			c.Request.Method,
 			c.Request.URL.Path,
 			c.Writer.Status(),
 			duration,
+			c.GetString("requestID"),
 		)
 	}
 }
 
+// RecoveryMiddleware перехватывает панику обработчика: пишет в журнал ошибку
+// со стеком и id запроса и отвечает 500 через onError. Если ответ уже начат,
+// соединение просто закрывается после записанного. Обрыв соединения клиентом
+// не считается ошибкой сервера
+func RecoveryMiddleware(onError ErrorFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// http.ErrAbortHandler - штатный способ прервать ответ, его обрабатывает net/http
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+			if err, ok := recovered.(error); ok && isBrokenPipe(err) {
+				log.Printf("Client disconnected: %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+				c.Abort()
+				return
+			}
+
+			log.Printf("🔥 Panic recovered [%s] %s %s: %v\n%s",
+				c.GetString("requestID"), c.Request.Method, c.Request.URL.Path, recovered, debug.Stack())
+			onError(c, http.StatusInternalServerError, "")
+		}()
+		c.Next()
+	}
+}
+
+// isBrokenPipe оборвал ли клиент соединение до конца ответа
+func isBrokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET)
+}
+
 // CORSMiddleware добавляет заголовки CORS
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -42,3 +134,105 @@ func CORSMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// AdminAuthMiddleware пускает к служебным эндпоинтам только запросы
+// с заголовком "Authorization: Bearer <token>". Пустой токен выключает
+// служебные эндпоинты целиком, чтобы они не оказались открытыми по ошибке
+func AdminAuthMiddleware(token string, onError ErrorFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			onError(c, http.StatusNotFound, "admin API is disabled")
+			return
+		}
+
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			onError(c, http.StatusUnauthorized, "")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AdminPageAuthMiddleware закрывает HTML-страницы админки. Браузер не умеет
+// отправлять Bearer, поэтому кроме него принимается Basic-авторизация: логин
+// любой, пароль - токен. Пустой токен выключает страницы, как и служебный API
+func AdminPageAuthMiddleware(token string, onError ErrorFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			onError(c, http.StatusNotFound, "")
+			return
+		}
+
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok {
+			_, provided, ok = c.Request.BasicAuth()
+		}
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Basic realm="admin", charset="UTF-8"`)
+			onError(c, http.StatusUnauthorized, "")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// SiteURLMiddleware кладет в контекст резолвер публичных адресов ("siteURL").
+// С redirect == true запросы GET и HEAD по ненормализованному пути ("/about/",
+// "//about") получают 301 на канонический путь. Запросы, совпавшие с маршрутом,
+// не трогаются: в wildcard-параметрах ("/img/:preset/*path") "//" бывает значимым
+func SiteURLMiddleware(resolver *siteurl.Resolver, redirect bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("siteURL", resolver)
+
+		method := c.Request.Method
+		if redirect && c.FullPath() == "" && (method == http.MethodGet || method == http.MethodHead) {
+			if clean := siteurl.CleanPath(c.Request.URL.Path); clean != c.Request.URL.Path {
+				target := clean
+				if c.Request.URL.RawQuery != "" {
+					target += "?" + c.Request.URL.RawQuery
+				}
+				c.Redirect(http.StatusMovedPermanently, target)
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// LocaleMiddleware определяет язык запроса (префикс URL, cookie, Accept-Language)
+// и кладет его в контекст gin ("locale") и в контекст запроса для i18n.T и шаблонов
+func LocaleMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setLocale(c, i18n.Detect(c.Request))
+		c.Next()
+	}
+}
+
+// PageLocaleMiddleware задает язык страницы по ее адресу: "/about" всегда на
+// языке по умолчанию, "/en/about" - на английском, независимо от cookie и
+// Accept-Language. Иначе по одному адресу отдавались бы разные тексты, и
+// поисковики не смогли бы сопоставить canonical и hreflang. Выбранный язык
+// запоминается в cookie, чтобы API и страница 404 отвечали на нем же
+func PageLocaleMiddleware(locale string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setLocale(c, locale)
+		if cookie, err := c.Cookie(i18n.CookieName); err != nil || cookie != locale {
+			c.SetSameSite(http.SameSiteLaxMode)
+			c.SetCookie(i18n.CookieName, locale, 365*24*60*60, "/", "", false, false)
+		}
+		c.Next()
+	}
+}
+
+// setLocale сохраняет язык запроса
+func setLocale(c *gin.Context, locale string) {
+	c.Set("locale", locale)
+	c.Request = c.Request.WithContext(i18n.WithLocale(c.Request.Context(), locale))
+	c.Header("Content-Language", locale)
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// ContactMessage сообщение, отправленное через форму обратной связи
+type ContactMessage struct {
+	ID        uint      `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Email     string    `json:"email" db:"email"`
+	Message   string    `json:"message" db:"message"`
+	IP        string    `json:"ip" db:"ip"`                 // адрес отправителя, для разбора спама
+	UserAgent string    `json:"user_agent" db:"user_agent"` // браузер отправителя
+	Locale    string    `json:"locale" db:"locale"`         // язык страницы, с которой пришло сообщение
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
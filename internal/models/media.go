@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// Media загруженный файл изображения
+type Media struct {
+	ID          uint      `json:"id" db:"id"`
+	OwnerID     *uint     `json:"owner_id,omitempty" db:"owner_id"` // пользователь, загрузивший файл; пока загрузки без владельца
+	StorageKey  string    `json:"storage_key" db:"storage_key"`     // ключ в хранилище: uploads/ab/<sha256>.png
+	URL         string    `json:"url" db:"url"`
+	ContentType string    `json:"content_type" db:"content_type"`
+	Size        int64     `json:"size" db:"size"`
+	Width       int       `json:"width" db:"width"`
+	Height      int       `json:"height" db:"height"`
+	AltText     string    `json:"alt_text" db:"alt_text"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
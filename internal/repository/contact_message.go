@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"gin-starter/internal/models"
+)
+
+// ContactMessageRepository интерфейс для работы с сообщениями формы обратной связи
+type ContactMessageRepository interface {
+	Create(message *models.ContactMessage) error
+	// List возвращает страницу сообщений (новые первыми) и общее число сообщений
+	List(limit, offset int) ([]*models.ContactMessage, int, error)
+}
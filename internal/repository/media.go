@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"gin-starter/internal/models"
+)
+
+// MediaRepository интерфейс для работы с загруженными файлами
+type MediaRepository interface {
+	Create(media *models.Media) error
+	GetByID(id uint) (*models.Media, error)
+	GetAll() ([]*models.Media, error)
+	Delete(id uint) error
+	// StorageKeyInUse сообщает, ссылается ли на файл в хранилище хоть одна запись
+	StorageKeyInUse(key string) (bool, error)
+}
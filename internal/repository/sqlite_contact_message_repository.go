@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"gin-starter/internal/models"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteContactMessageRepository реализация репозитория сообщений для SQLite
+type SQLiteContactMessageRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteContactMessageRepository создает новый экземпляр репозитория
+func NewSQLiteContactMessageRepository(db *sql.DB) *SQLiteContactMessageRepository {
+	return &SQLiteContactMessageRepository{
+		db: db,
+	}
+}
+
+// Create сохраняет сообщение
+func (r *SQLiteContactMessageRepository) Create(message *models.ContactMessage) error {
+	query := `
+		INSERT INTO contact_messages (name, email, message, ip, user_agent, locale, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, datetime('now'))
+	`
+
+	result, err := r.db.Exec(query, message.Name, message.Email, message.Message,
+		message.IP, message.UserAgent, message.Locale)
+	if err != nil {
+		return fmt.Errorf("failed to insert contact message: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	message.ID = uint(id)
+
+	row := r.db.QueryRow("SELECT created_at FROM contact_messages WHERE id = ?", message.ID)
+	if err := row.Scan(&message.CreatedAt); err != nil {
+		return fmt.Errorf("failed to get contact message date: %w", err)
+	}
+
+	return nil
+}
+
+// List возвращает страницу сообщений, новые первыми; limit <= 0 - без ограничения
+func (r *SQLiteContactMessageRepository) List(limit, offset int) ([]*models.ContactMessage, int, error) {
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM contact_messages`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count contact messages: %w", err)
+	}
+
+	if limit <= 0 {
+		limit = -1 // в SQLite LIMIT -1 - без ограничения
+	}
+	query := `SELECT id, name, email, message, ip, user_agent, locale, created_at FROM contact_messages
+		ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`
+	rows, err := r.db.Query(query, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query contact messages: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var messages []*models.ContactMessage
+	for rows.Next() {
+		var m models.ContactMessage
+		err := rows.Scan(&m.ID, &m.Name, &m.Email, &m.Message, &m.IP, &m.UserAgent, &m.Locale, &m.CreatedAt)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan contact message: %w", err)
+		}
+		messages = append(messages, &m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read contact messages: %w", err)
+	}
+
+	return messages, total, nil
+}
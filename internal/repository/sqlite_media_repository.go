@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"gin-starter/internal/models"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// mediaColumns колонки таблицы media в порядке сканирования
+const mediaColumns = `id, owner_id, storage_key, url, content_type, size, width, height, alt_text, created_at`
+
+// SQLiteMediaRepository реализация репозитория файлов для SQLite
+type SQLiteMediaRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteMediaRepository создает новый экземпляр репозитория
+func NewSQLiteMediaRepository(db *sql.DB) *SQLiteMediaRepository {
+	return &SQLiteMediaRepository{
+		db: db,
+	}
+}
+
+// Create сохраняет запись о загруженном файле
+func (r *SQLiteMediaRepository) Create(media *models.Media) error {
+	query := `
+		INSERT INTO media (owner_id, storage_key, url, content_type, size, width, height, alt_text, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
+	`
+
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	result, err := stmt.Exec(media.OwnerID, media.StorageKey, media.URL, media.ContentType,
+		media.Size, media.Width, media.Height, media.AltText)
+	if err != nil {
+		return fmt.Errorf("failed to insert media: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	media.ID = uint(id)
+
+	// Устанавливаем дату создания
+	row := r.db.QueryRow("SELECT created_at FROM media WHERE id = ?", media.ID)
+	if err := row.Scan(&media.CreatedAt); err != nil {
+		return fmt.Errorf("failed to get media dates: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID возвращает запись о файле по ID
+func (r *SQLiteMediaRepository) GetByID(id uint) (*models.Media, error) {
+	query := `SELECT ` + mediaColumns + ` FROM media WHERE id = ?`
+
+	media, err := scanMedia(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("media with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to get media: %w", err)
+	}
+
+	return media, nil
+}
+
+// GetAll возвращает все загруженные файлы, новые первыми
+func (r *SQLiteMediaRepository) GetAll() ([]*models.Media, error) {
+	query := `SELECT ` + mediaColumns + ` FROM media ORDER BY created_at DESC, id DESC`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query media: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var items []*models.Media
+	for rows.Next() {
+		media, err := scanMedia(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan media: %w", err)
+		}
+		items = append(items, media)
+	}
+
+	return items, rows.Err()
+}
+
+// Delete удаляет запись о файле по ID. Сам файл в хранилище не удаляется:
+// одинаковое содержимое хранится под одним ключом и может использоваться другими записями
+func (r *SQLiteMediaRepository) Delete(id uint) error {
+	query := `DELETE FROM media WHERE id = ?`
+
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	if _, err := stmt.Exec(id); err != nil {
+		return fmt.Errorf("failed to delete media: %w", err)
+	}
+
+	return nil
+}
+
+// StorageKeyInUse сообщает, есть ли записи с этим ключом хранилища
+func (r *SQLiteMediaRepository) StorageKeyInUse(key string) (bool, error) {
+	var exists bool
+	if err := r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM media WHERE storage_key = ?)`, key).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check storage key: %w", err)
+	}
+	return exists, nil
+}
+
+// scanMedia читает одну запись media из строки результата
+func scanMedia(row interface{ Scan(dest ...any) error }) (*models.Media, error) {
+	var media models.Media
+	var ownerID sql.NullInt64
+	err := row.Scan(&media.ID, &ownerID, &media.StorageKey, &media.URL, &media.ContentType,
+		&media.Size, &media.Width, &media.Height, &media.AltText, &media.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if ownerID.Valid {
+		owner := uint(ownerID.Int64)
+		media.OwnerID = &owner
+	}
+	return &media, nil
+}
@@ -2,8 +2,11 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"gin-starter/internal/models"
+	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -118,6 +121,57 @@ func (r *SQLiteUserRepository) GetAll() ([]*models.User, error) {
 	return users, nil
 }
 
+// List возвращает страницу пользователей и общее число найденных. Поиск идет
+// по LIKE: в SQLite он не различает регистр только для латиницы
+func (r *SQLiteUserRepository) List(opts UserListOptions) ([]*models.User, int, error) {
+	where := ""
+	var args []any
+	if opts.Query != "" {
+		pattern := "%" + escapeLike(opts.Query) + "%"
+		where = ` WHERE name LIKE ? ESCAPE '\' OR email LIKE ? ESCAPE '\'`
+		args = append(args, pattern, pattern)
+	}
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM users`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = -1 // в SQLite LIMIT -1 - без ограничения
+	}
+	query := `SELECT id, name, email, created_at, updated_at FROM users` + where +
+		` ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`
+	rows, err := r.db.Query(query, append(args, limit, opts.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var users []*models.User
+	for rows.Next() {
+		var user models.User
+		err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, &user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read users: %w", err)
+	}
+
+	return users, total, nil
+}
+
+// escapeLike экранирует спецсимволы LIKE, чтобы "%" и "_" в запросе искались буквально
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 // Update обновляет пользователя
 func (r *SQLiteUserRepository) Update(user *models.User) error {
 	query := `
@@ -161,3 +215,17 @@ func (r *SQLiteUserRepository) Delete(id uint) error {
 
 	return nil
 }
+
+// LastModified возвращает время последнего изменения пользователей. Берется сама
+// колонка, а не MAX(): у агрегата драйвер не знает тип и отдает строку
+func (r *SQLiteUserRepository) LastModified() (time.Time, error) {
+	var updatedAt time.Time
+	err := r.db.QueryRow(`SELECT updated_at FROM users ORDER BY updated_at DESC LIMIT 1`).Scan(&updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get users last modified: %w", err)
+	}
+	return updatedAt, nil
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"gin-starter/internal/models"
+	"time"
 )
 
 // UserRepository интерфейс для работы с пользователями
@@ -10,6 +11,17 @@ type UserRepository interface {
 	GetByID(id uint) (*models.User, error)
 	GetByEmail(email string) (*models.User, error)
 	GetAll() ([]*models.User, error)
+	// List страница пользователей (новые первыми) и общее число найденных
+	List(opts UserListOptions) ([]*models.User, int, error)
 	Update(user *models.User) error
 	Delete(id uint) error
+	// LastModified время последнего изменения пользователей; нулевое, если их нет
+	LastModified() (time.Time, error)
+}
+
+// UserListOptions параметры постраничной выборки пользователей
+type UserListOptions struct {
+	Query  string // подстрока имени или email; пусто - все пользователи
+	Limit  int    // 0 - без ограничения
+	Offset int
 }
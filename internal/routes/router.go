@@ -2,6 +2,9 @@ package routes
 
 import (
 	"gin-starter/internal/handlers"
+	"gin-starter/internal/i18n"
+	"gin-starter/internal/middleware"
+	"gin-starter/internal/service/sitemap"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/secure"
@@ -9,7 +12,7 @@ import (
 )
 
 // Обратите внимание: я разделил handlers на pageHandler и userApiHandler
-func SetupRoutes(r *gin.Engine, pageHandler *handlers.PageHandler, userApiHandler *handlers.UserHandler, imageHandler *handlers.ImageHandler) {
+func SetupRoutes(r *gin.Engine, pageHandler *handlers.PageHandler, userApiHandler *handlers.UserHandler, contactHandler *handlers.ContactHandler, imageHandler *handlers.ImageHandler, mediaHandler *handlers.MediaHandler, sitemapHandler *handlers.SitemapHandler, robotsHandler *handlers.RobotsHandler, adminAuth gin.HandlerFunc, adminPageAuth gin.HandlerFunc) {
 
 	// 1. Безопасность (через библиотеку надежнее)
 	r.Use(secure.New(secure.Config{
@@ -21,17 +24,57 @@ func SetupRoutes(r *gin.Engine, pageHandler *handlers.PageHandler, userApiHandle
 	// 2. CORS (если нужно взаимодействие с внешним фронтендом)
 	r.Use(cors.Default())
 
-	// 3. Web-страницы (HTML)
+	// 3. Web-страницы (HTML). Каждая страница регистрируется на всех языках
+	// ("/about", "/en/about") и сразу попадает в sitemap.xml со своими changefreq и priority.
+	// Страница без sitemap попадает в карту сайта только через источник ссылок:
+	// /users без базы данных не работает, и ее добавляет handlers.UsersSitemapSource
+	pages := []struct {
+		path    string
+		handler gin.HandlerFunc
+		sitemap *sitemap.Page
+	}{
+		{"/", pageHandler.Home, &sitemap.Page{ChangeFreq: sitemap.Weekly, Priority: 1.0}},
+		{"/about", pageHandler.About, &sitemap.Page{ChangeFreq: sitemap.Monthly, Priority: 0.8}},
+		{"/contact", contactHandler.Show, &sitemap.Page{ChangeFreq: sitemap.Monthly, Priority: 0.8}},
+		{"/users", pageHandler.Users, nil},
+	}
 	web := r.Group("/")
 	{
-		web.GET("/", pageHandler.Home)
-		web.GET("/about", pageHandler.About)
-		web.GET("/contact", pageHandler.Contact)
-		web.GET("/users", pageHandler.Users)
+		for _, locale := range i18n.Locales {
+			pageLocale := middleware.PageLocaleMiddleware(locale)
+			for _, page := range pages {
+				path := i18n.LocalizePath(locale, page.path)
+				web.GET(path, pageLocale, page.handler)
+				if page.sitemap != nil {
+					sitemapHandler.AddPage(path, *page.sitemap)
+				}
+			}
+
+			// Формы страницы пользователей: работают без JS и отдают фрагменты htmx
+			usersPath := i18n.LocalizePath(locale, "/users")
+			web.POST(usersPath, pageLocale, pageHandler.CreateUserForm)
+			web.POST(usersPath+"/:id/delete", pageLocale, pageHandler.DeleteUserForm)
+
+			// Форма обратной связи
+			web.POST(i18n.LocalizePath(locale, "/contact"), pageLocale, contactHandler.Submit)
+		}
 	}
 
+	// Страницы админки: без языковых версий и sitemap, вход по ADMIN_TOKEN
+	adminPages := r.Group("/admin", adminPageAuth)
+	{
+		adminPages.GET("/contact-messages", contactHandler.AdminList)
+	}
+
+	// Карта сайта: при больших объемах /sitemap.xml становится индексом частей
+	r.GET("/robots.txt", robotsHandler.Robots)
+	r.GET("/sitemap.xml", sitemapHandler.Sitemap)
+	r.GET(handlers.SitemapRoute+"/:file", sitemapHandler.Part)
+
 	// 4. Отдельный роут для картинок
 	r.GET("/optimized-image", imageHandler.OptimizedImage)
+	r.GET("/optimized-image/placeholder", imageHandler.Placeholder)
+	r.GET("/img/:preset/*path", imageHandler.Preset)
 
 	// 5. API (JSON) с версионированием
 	api := r.Group("/api/v1")
@@ -40,6 +83,15 @@ func SetupRoutes(r *gin.Engine, pageHandler *handlers.PageHandler, userApiHandle
 		api.GET("/users", userApiHandler.GetUsers)
 		api.POST("/users", userApiHandler.CreateUser)
 		api.DELETE("/users/:id", userApiHandler.DeleteUser)
+
+		// Загрузка пишет в публичный каталог или бакет, поэтому только с авторизацией
+		api.POST("/media", adminAuth, mediaHandler.Upload)
+
+		// Служебные эндпоинты закрыты токеном (ADMIN_TOKEN)
+		admin := api.Group("/admin", adminAuth)
+		{
+			admin.GET("/image-presets", imageHandler.ListPresets)
+		}
 	}
 
 	// 6. Обработчик 404 для всех остальных маршрутов
@@ -0,0 +1,364 @@
+package image
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+)
+
+// Animation многокадровое изображение: кадры уже собраны в полный холст
+type Animation struct {
+	Frames    []image.Image
+	Delays    []int  // задержки кадров в сотых долях секунды
+	Disposal  []byte // способ очистки после кадра (gif.DisposalNone и т.п.)
+	LoopCount int    // 0 - бесконечно, -1 - без повторов
+}
+
+// AnimatedEncodeFunc кодирует анимацию в конкретный формат
+type AnimatedEncodeFunc func(w io.Writer, anim *Animation, quality int) error
+
+// animatedEncoders форматы, в которых анимация сохраняется. Это только GIF:
+// кодировщика WebP нет (см. encoders), поэтому анимированного WebP на выходе
+// тоже нет, а fm=webp отклоняется еще при разборе параметров. Без fm
+// (FormatDefault) анимированный GIF остается GIF, а с явным fm=jpeg или png
+// отдается первым кадром. Анимированные WebP-исходники golang.org/x/image/webp
+// не декодирует, анимация читается только из GIF
+var animatedEncoders = map[Format]AnimatedEncodeFunc{
+	FormatGIF: encodeAnimatedGIF,
+}
+
+// animatedEncoder возвращает кодировщик анимации формата, если он есть
+func (f Format) animatedEncoder() (AnimatedEncodeFunc, bool) {
+	fn, ok := animatedEncoders[f]
+	return fn, ok
+}
+
+// processAnimation обрабатывает анимированный исходник с сохранением всех кадров.
+// Возвращает ok == false, если исходник не анимирован или формат не умеет анимацию:
+// тогда обрабатывается как обычное изображение (первый кадр)
+func (ps *ProcessorService) processAnimation(ctx context.Context, src source, opts ProcessOptions) ([]byte, bool, error) {
+	if opts.Poster {
+		return nil, false, nil
+	}
+	encode, ok := opts.Format.animatedEncoder()
+	if !ok {
+		return nil, false, nil
+	}
+
+	frames, release, err := ps.openGIF(ctx, src, opts)
+	if err != nil || frames == nil {
+		return nil, false, err
+	}
+	defer release()
+
+	anim, err := compositeFrames(ctx, frames, func(frame image.Image) image.Image {
+		return ps.overlays.Apply(ps.resizeImage(frame, opts.Width, opts.Height, opts.Fit), opts)
+	})
+	if err != nil {
+		return nil, true, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, true, err
+	}
+
+	var buf bytes.Buffer
+	if err := encode(contextWriter{ctx: ctx, w: &buf}, anim, opts.Quality); err != nil {
+		return nil, true, err
+	}
+	return buf.Bytes(), true, nil
+}
+
+// isAnimated сообщает, многокадровый ли GIF исходник. Кадры считаются по
+// структуре файла, без декодирования; ответ запоминается в кэше вариантов
+// под версией исходника, поэтому файл читается один раз на версию
+func (ps *ProcessorService) isAnimated(ctx context.Context, src source, meta *SourceMeta) (bool, error) {
+	key := animationKey(src.name, meta.Version)
+	if data, found := ps.cache.Get(key); found && len(data) == 1 {
+		return data[0] == 1, nil
+	}
+
+	file, err := src.loader.Open(ctx, src.name)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var animated bool
+	reader := bufio.NewReader(file)
+	if header, err := reader.Peek(6); err == nil && (string(header) == "GIF87a" || string(header) == "GIF89a") {
+		// Битый GIF не анимация: ошибку покажет сама обработка
+		frames, _, _, err := scanGIF(reader)
+		animated = err == nil && frames > 1
+	}
+
+	flag := []byte{0}
+	if animated {
+		flag[0] = 1
+	}
+	ps.cache.Set(key, flag)
+	return animated, nil
+}
+
+// animationKey ключ кэша для признака анимации исходника
+func animationKey(name, version string) string {
+	hash := md5.Sum([]byte(name + "_" + version + "_animated"))
+	return hex.EncodeToString(hash[:])
+}
+
+// openGIF декодирует все кадры GIF. Для не-GIF и однокадровых GIF возвращает nil.
+// Число кадров и их суммарная площадь проверяются по структуре файла до
+// декодирования: иначе маленький GIF из тысяч кадров занял бы гигабайты памяти.
+// Оценка памяти на всю покадровую обработку занимается из бюджета; release возвращает ее
+func (ps *ProcessorService) openGIF(ctx context.Context, src source, opts ProcessOptions) (*gif.GIF, func(), error) {
+	file, err := src.loader.Open(ctx, src.name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	reader := bufio.NewReader(file)
+	header, err := reader.Peek(6)
+	if err != nil || (string(header) != "GIF87a" && string(header) != "GIF89a") {
+		return nil, nil, nil
+	}
+
+	frames, width, height, err := scanGIF(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if frames <= 1 {
+		return nil, nil, nil
+	}
+	pixels := int64(frames) * int64(width) * int64(height)
+	if ps.maxFrames > 0 && frames > ps.maxFrames {
+		return nil, nil, fmt.Errorf("%w: %d frames, limit is %d", ErrImageTooLarge, frames, ps.maxFrames)
+	}
+	if ps.maxAnimationPixels > 0 && pixels > ps.maxAnimationPixels {
+		return nil, nil, fmt.Errorf("%w: %d pixels in all frames", ErrImageTooLarge, pixels)
+	}
+	if err := ps.checkPixels(width, height); err != nil {
+		return nil, nil, err
+	}
+
+	release, err := ps.memory.reserve(ctx, estimateAnimationMemory(width, height, frames, pixels, opts), ps.queueTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	anim, err := decodeGIF(ctx, src)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return anim, release, nil
+}
+
+// decodeGIF декодирует все кадры GIF; отмена ctx прерывает чтение
+func decodeGIF(ctx context.Context, src source) (*gif.GIF, error) {
+	file, err := src.loader.Open(ctx, src.name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	return gif.DecodeAll(contextReader{ctx: ctx, r: file})
+}
+
+// errGIFStructure нарушена блочная структура GIF
+var errGIFStructure = errors.New("gif: malformed block structure")
+
+// scanGIF проходит по блокам GIF без декодирования LZW и возвращает число
+// кадров и размер холста: растр каждого кадра после декодирования занимает весь холст
+func scanGIF(r *bufio.Reader) (frames, width, height int, err error) {
+	var screen [13]byte // заголовок (6) + дескриптор логического экрана (7)
+	if _, err := io.ReadFull(r, screen[:]); err != nil {
+		return 0, 0, 0, err
+	}
+	width = int(screen[6]) | int(screen[7])<<8
+	height = int(screen[8]) | int(screen[9])<<8
+	if screen[10]&0x80 != 0 {
+		if _, err := r.Discard(3 << (int(screen[10]&0x07) + 1)); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+
+	for {
+		introducer, err := r.ReadByte()
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		switch introducer {
+		case 0x3B: // конец файла
+			return frames, width, height, nil
+		case 0x21: // расширение: метка и подблоки
+			if _, err := r.ReadByte(); err != nil {
+				return 0, 0, 0, err
+			}
+			if err := skipSubBlocks(r); err != nil {
+				return 0, 0, 0, err
+			}
+		case 0x2C: // дескриптор кадра
+			var desc [9]byte
+			if _, err := io.ReadFull(r, desc[:]); err != nil {
+				return 0, 0, 0, err
+			}
+			if desc[8]&0x80 != 0 {
+				if _, err := r.Discard(3 << (int(desc[8]&0x07) + 1)); err != nil {
+					return 0, 0, 0, err
+				}
+			}
+			if _, err := r.ReadByte(); err != nil { // минимальный размер кода LZW
+				return 0, 0, 0, err
+			}
+			if err := skipSubBlocks(r); err != nil {
+				return 0, 0, 0, err
+			}
+			frames++
+		default:
+			return 0, 0, 0, errGIFStructure
+		}
+	}
+}
+
+// skipSubBlocks пропускает цепочку подблоков до нулевого
+func skipSubBlocks(r *bufio.Reader) error {
+	for {
+		size, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if size == 0 {
+			return nil
+		}
+		if _, err := r.Discard(int(size)); err != nil {
+			return err
+		}
+	}
+}
+
+// compositeFrames собирает кадры GIF в полные холсты и сразу применяет к ним
+// transform (ресайз), чтобы в памяти не копились кадры исходного размера.
+// Кадр GIF может обновлять только часть холста, поэтому уменьшать кадры по
+// отдельности нельзя: на стыках появятся швы. Способ очистки после кадра
+// сохраняется: для полных кадров он дает тот же результат, что и для частичных
+func compositeFrames(ctx context.Context, g *gif.GIF, transform func(image.Image) image.Image) (*Animation, error) {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	canvas := image.NewNRGBA(bounds)
+	transparent := image.NewUniform(color.Transparent)
+
+	anim := &Animation{
+		Frames:    make([]image.Image, len(g.Image)),
+		Delays:    make([]int, len(g.Image)),
+		Disposal:  make([]byte, len(g.Image)),
+		LoopCount: g.LoopCount,
+	}
+
+	for i, frame := range g.Image {
+		// Кадров может быть много: даем отменить обработку между ними
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if i < len(g.Delay) {
+			anim.Delays[i] = g.Delay[i]
+		}
+		anim.Disposal[i] = disposal
+
+		var previous *image.NRGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneNRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		anim.Frames[i] = transform(canvas)
+		if anim.Frames[i] == image.Image(canvas) {
+			// Без ресайза transform возвращает тот же холст, а он меняется дальше
+			anim.Frames[i] = cloneNRGBA(canvas)
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return anim, nil
+}
+
+// cloneNRGBA копирует холст
+func cloneNRGBA(src *image.NRGBA) *image.NRGBA {
+	dst := image.NewNRGBA(src.Rect)
+	copy(dst.Pix, src.Pix)
+	return dst
+}
+
+// encodeAnimatedGIF кодирует анимацию в GIF. Кадры после ресайза содержат
+// новые цвета, поэтому переводятся в общую палитру Plan9 с дизерингом
+func encodeAnimatedGIF(w io.Writer, anim *Animation, _ int) error {
+	out := &gif.GIF{
+		Image:     make([]*image.Paletted, len(anim.Frames)),
+		Delay:     anim.Delays,
+		Disposal:  anim.Disposal,
+		LoopCount: anim.LoopCount,
+	}
+
+	for i, frame := range anim.Frames {
+		out.Image[i] = palettedFrame(frame)
+	}
+	if len(out.Image) > 0 {
+		bounds := out.Image[0].Bounds()
+		out.Config = image.Config{Width: bounds.Dx(), Height: bounds.Dy()}
+	}
+	return gif.EncodeAll(w, out)
+}
+
+// gifPalette палитра кадров: 255 цветов Plan9 и прозрачный цвет последним
+var gifPalette = append(append(color.Palette{}, palette.Plan9[:255]...), color.Transparent)
+
+// palettedFrame переводит кадр в палитру GIF. Полупрозрачность GIF не умеет,
+// поэтому альфа округляется до 0 или 255 перед дизерингом
+func palettedFrame(frame image.Image) *image.Paletted {
+	bounds := frame.Bounds()
+	nrgba := image.NewNRGBA(bounds)
+	draw.Draw(nrgba, bounds, frame, bounds.Min, draw.Src)
+
+	transparentIndex := uint8(len(gifPalette) - 1)
+	mask := make([]bool, bounds.Dx()*bounds.Dy())
+	for i := 0; i < len(nrgba.Pix); i += 4 {
+		if nrgba.Pix[i+3] < 128 {
+			mask[i/4] = true
+		}
+		nrgba.Pix[i+3] = 255
+	}
+
+	paletted := image.NewPaletted(bounds, gifPalette[:transparentIndex])
+	draw.FloydSteinberg.Draw(paletted, bounds, nrgba, bounds.Min)
+	paletted.Palette = gifPalette
+	for i, transparent := range mask {
+		if transparent {
+			paletted.Pix[(i/bounds.Dx())*paletted.Stride+i%bounds.Dx()] = transparentIndex
+		}
+	}
+	return paletted
+}
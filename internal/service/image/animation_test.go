@@ -0,0 +1,110 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// testAnimatedGIF кодирует GIF из трех кадров разного цвета
+func testAnimatedGIF(t *testing.T) []byte {
+	t.Helper()
+	pal := color.Palette{color.Black, color.White, color.RGBA{R: 255, A: 255}}
+	anim := &gif.GIF{LoopCount: 0}
+	for i := range 3 {
+		frame := image.NewPaletted(image.Rect(0, 0, 20, 10), pal)
+		for j := range frame.Pix {
+			frame.Pix[j] = uint8(i)
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatalf("gif.EncodeAll: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestAnimatedGIFKeepsFramesOnlyInGIF(t *testing.T) {
+	ps := NewProcessorService(ProcessorOptions{Media: fstest.MapFS{
+		"images/anim.gif": {Data: testAnimatedGIF(t)},
+	}})
+	ctx := context.Background()
+
+	data, err := ps.ProcessImage(ctx, "/static/images/anim.gif", ProcessOptions{Width: 10, Quality: 80, Format: FormatGIF})
+	if err != nil {
+		t.Fatalf("ProcessImage(gif): %v", err)
+	}
+	anim, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	if len(anim.Image) != 3 || anim.Config.Width != 10 {
+		t.Fatalf("gif result = %d frames %dpx wide, want 3 frames 10px wide", len(anim.Image), anim.Config.Width)
+	}
+
+	// Форматы без анимации получают первый кадр
+	data, err = ps.ProcessImage(ctx, "/static/images/anim.gif", ProcessOptions{Width: 10, Quality: 80, Format: FormatPNG})
+	if err != nil {
+		t.Fatalf("ProcessImage(png): %v", err)
+	}
+	if _, format, err := image.DecodeConfig(bytes.NewReader(data)); err != nil || format != "png" {
+		t.Fatalf("png result format = %q, %v; want png", format, err)
+	}
+}
+
+func TestDefaultFormatKeepsAnimation(t *testing.T) {
+	cache := NewImageCache(time.Hour, 1<<20)
+	t.Cleanup(func() {
+		_ = cache.Close()
+	})
+	ps := NewProcessorService(ProcessorOptions{
+		Media: fstest.MapFS{
+			"images/anim.gif": {Data: testAnimatedGIF(t)},
+			"images/a.png":    {Data: testPNG(t, 20, 10)},
+		},
+		Cache: cache,
+	})
+	ctx := context.Background()
+
+	// Без fm анимированный GIF остается анимированным GIF
+	variant, err := ps.Variant(ctx, "/static/images/anim.gif", ProcessOptions{Width: 10, Quality: 80})
+	if err != nil {
+		t.Fatalf("Variant: %v", err)
+	}
+	if variant.Format != FormatGIF {
+		t.Fatalf("Variant().Format = %q, want gif for animated source", variant.Format)
+	}
+	data, err := ps.ProcessImage(ctx, "/static/images/anim.gif", ProcessOptions{Width: 10, Quality: 80})
+	if err != nil {
+		t.Fatalf("ProcessImage: %v", err)
+	}
+	anim, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil || len(anim.Image) != 3 {
+		t.Fatalf("default format result: %v, want 3-frame GIF", err)
+	}
+
+	// Постер и статичные исходники по умолчанию кодируются в JPEG
+	for path, opts := range map[string]ProcessOptions{
+		"/static/images/anim.gif": {Width: 10, Quality: 80, Poster: true},
+		"/static/images/a.png":    {Width: 10, Quality: 80},
+	} {
+		variant, err := ps.Variant(ctx, path, opts)
+		if err != nil || variant.Format != FormatJPEG {
+			t.Fatalf("Variant(%s, %+v) = %+v, %v; want jpeg", path, opts, variant, err)
+		}
+		data, err := ps.ProcessImage(ctx, path, opts)
+		if err != nil {
+			t.Fatalf("ProcessImage(%s): %v", path, err)
+		}
+		if _, format, err := image.DecodeConfig(bytes.NewReader(data)); err != nil || format != "jpeg" {
+			t.Fatalf("ProcessImage(%s, %+v) format = %q, %v; want jpeg", path, opts, format, err)
+		}
+	}
+}
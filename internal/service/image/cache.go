@@ -1,89 +1,206 @@
 package image
 
 import (
+	"container/list"
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Cache интерфейс кэша обработанных изображений.
+// Реализации: ImageCache (память), DiskCache (диск) и TieredCache (цепочка уровней)
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, data []byte)
+}
+
+// cacheShards количество сегментов in-memory кэша. Каждый сегмент со своим
+// мьютексом, поэтому параллельные запросы к разным ключам не ждут друг друга
+const cacheShards = 16
+
 // CacheItem представляет элемент кэша
 type CacheItem struct {
-	Data        []byte
-	CreatedAt   time.Time
-	AccessCount int
+	Key       string
+	Data      []byte
+	CreatedAt time.Time
+}
+
+// CacheStats статистика работы кэша
+type CacheStats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	Bytes     int64 `json:"bytes"`
+	Items     int64 `json:"items"`
+}
+
+// cacheShard сегмент кэша: LRU-список и индекс по ключу под одним мьютексом
+type cacheShard struct {
+	mutex    sync.Mutex
+	items    map[string]*list.Element
+	lru      *list.List // от недавно использованных к давно неиспользованным
+	size     int64      // текущий суммарный размер данных
+	maxBytes int64      // бюджет сегмента в байтах
 }
 
-// ImageCache простая in-memory кэш-система для изображений
+// ImageCache in-memory LRU-кэш изображений с бюджетом в байтах.
+// Бюджет делится поровну между сегментами, вытесняется давно не использованное
 type ImageCache struct {
-	items   map[string]*CacheItem
-	mutex   sync.RWMutex
-	ttl     time.Duration // время жизни кэша
-	maxSize int           // максимальный размер кэша в элементах
+	shards [cacheShards]*cacheShard
+	ttl    time.Duration // время жизни кэша
+
+	stop      chan struct{} // закрывается в Close и останавливает очистку
+	closeOnce sync.Once
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+}
+
+// CacheOptions настройки кэша изображений
+type CacheOptions struct {
+	TTL         time.Duration // время жизни элемента в памяти
+	MemoryBytes int64         // бюджет памяти в байтах
+	DiskDir     string        // каталог дискового кэша; пустая строка отключает диск
+	DiskBytes   int64         // бюджет диска в байтах
+}
+
+// NewImageCache создает in-memory кэш с бюджетом в байтах
+func NewImageCache(ttl time.Duration, maxBytes int64) *ImageCache {
+	c := &ImageCache{ttl: ttl, stop: make(chan struct{})}
+	for i := range c.shards {
+		c.shards[i] = &cacheShard{
+			items:    make(map[string]*list.Element),
+			lru:      list.New(),
+			maxBytes: maxBytes / cacheShards,
+		}
+	}
+
+	// Запускаем очистку устаревших элементов; останавливается в Close
+	go c.cleanup()
+
+	return c
 }
 
-// GlobalCache глобальный экземпляр кэша
-var GlobalCache *ImageCache
+// NewCache создает кэш изображений по настройкам: память и, если задан каталог, диск за ней.
+// При ошибке диска возвращается рабочий кэш в памяти вместе с ошибкой.
+// Кэш закрывается при остановке приложения (см. CloseCache)
+func NewCache(opts CacheOptions) (Cache, error) {
+	memory := NewImageCache(opts.TTL, opts.MemoryBytes)
+	if opts.DiskDir == "" {
+		return memory, nil
+	}
 
-// InitCache инициализирует глобальный кэш
-func InitCache(ttl time.Duration, maxSize int) {
-	GlobalCache = &ImageCache{
-		items:   make(map[string]*CacheItem),
-		ttl:     ttl,
-		maxSize: maxSize,
+	disk, err := NewDiskCache(opts.DiskDir, opts.DiskBytes)
+	if err != nil {
+		// Без диска кэш продолжает работать только в памяти
+		return memory, fmt.Errorf("failed to init disk cache: %w", err)
 	}
 
-	// Запускаем очистку устаревших элементов
-	go GlobalCache.cleanup()
+	return NewTieredCache(memory, disk), nil
+}
+
+// CloseCache останавливает фоновые задачи кэша, если они у него есть
+func CloseCache(cache Cache) error {
+	if closer, ok := cache.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
 }
 
 // Get получает элемент из кэша
 func (c *ImageCache) Get(key string) ([]byte, bool) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
+	shard := c.shard(key)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
 
-	item, exists := c.items[key]
+	elem, exists := shard.items[key]
 	if !exists {
+		c.misses.Add(1)
 		return nil, false
 	}
 
 	// Проверяем, не истекло ли время жизни
+	item := elem.Value.(*CacheItem)
 	if time.Since(item.CreatedAt) > c.ttl {
-		// Удаляем устаревший элемент
-		delete(c.items, key)
+		shard.remove(elem)
+		c.misses.Add(1)
 		return nil, false
 	}
 
-	// Увеличиваем счетчик обращений
-	item.AccessCount++
+	shard.lru.MoveToFront(elem)
+	c.hits.Add(1)
 	return item.Data, true
 }
 
 // Set сохраняет элемент в кэше
 func (c *ImageCache) Set(key string, data []byte) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	shard := c.shard(key)
+
+	// Элемент больше бюджета сегмента не поместится никогда
+	size := int64(len(data))
+	if size > shard.maxBytes {
+		return
+	}
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
 
-	// Если кэш переполнен, удаляем самый старый элемент
-	if len(c.items) >= c.maxSize {
-		c.evictOldest()
+	if elem, exists := shard.items[key]; exists {
+		shard.remove(elem)
 	}
 
-	c.items[key] = &CacheItem{
+	// Освобождаем место, пока новый элемент не поместится в бюджет
+	for shard.size+size > shard.maxBytes {
+		oldest := shard.lru.Back()
+		if oldest == nil {
+			break
+		}
+		shard.remove(oldest)
+		c.evictions.Add(1)
+	}
+
+	shard.items[key] = shard.lru.PushFront(&CacheItem{
+		Key:       key,
 		Data:      data,
 		CreatedAt: time.Now(),
-	}
+	})
+	shard.size += size
 }
 
-// evictOldest удаляет самый старый элемент из кэша
-func (c *ImageCache) evictOldest() {
-	// В простой реализации удалим первый попавшийся элемент
-	// В реальной системе можно использовать LRU или другую стратегию
-	for key := range c.items {
-		delete(c.items, key)
-		break
+// Stats возвращает статистику кэша
+func (c *ImageCache) Stats() CacheStats {
+	stats := CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+	}
+	for _, shard := range c.shards {
+		shard.mutex.Lock()
+		stats.Bytes += shard.size
+		stats.Items += int64(len(shard.items))
+		shard.mutex.Unlock()
 	}
+	return stats
+}
+
+// shard выбирает сегмент по хэшу ключа
+func (c *ImageCache) shard(key string) *cacheShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return c.shards[h.Sum32()%cacheShards]
+}
+
+// remove удаляет элемент сегмента; вызывается под мьютексом сегмента
+func (s *cacheShard) remove(elem *list.Element) {
+	item := s.lru.Remove(elem).(*CacheItem)
+	delete(s.items, item.Key)
+	s.size -= int64(len(item.Data))
 }
 
 // cleanup периодически удаляет устаревшие элементы
@@ -91,52 +208,63 @@ func (c *ImageCache) cleanup() {
 	ticker := time.NewTicker(5 * time.Minute) // очистка каждые 5 минут
 	defer ticker.Stop()
 
-	for range ticker.C {
-		c.cleanupExpired()
+	for {
+		select {
+		case <-ticker.C:
+			c.cleanupExpired()
+		case <-c.stop:
+			return
+		}
 	}
 }
 
-// cleanupExpired удаляет устаревшие элементы
-func (c *ImageCache) cleanupExpired() {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+// Close останавливает фоновую очистку. Кэш остается рабочим, но устаревшие
+// элементы удаляются только при обращении к ним. Повторный вызов безопасен
+func (c *ImageCache) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+	})
+	return nil
+}
 
+// cleanupExpired удаляет устаревшие элементы. Элементы в конце LRU-списка
+// не обязательно самые старые по времени создания, поэтому проходим сегмент целиком
+func (c *ImageCache) cleanupExpired() {
 	now := time.Now()
-	for key, item := range c.items {
-		if now.Sub(item.CreatedAt) > c.ttl {
-			delete(c.items, key)
+	for _, shard := range c.shards {
+		shard.mutex.Lock()
+		for elem := shard.lru.Back(); elem != nil; {
+			prev := elem.Prev()
+			if now.Sub(elem.Value.(*CacheItem).CreatedAt) > c.ttl {
+				shard.remove(elem)
+			}
+			elem = prev
 		}
+		shard.mutex.Unlock()
 	}
 }
 
-// GenerateCacheKey генерирует уникальный ключ для кэширования изображения
-func GenerateCacheKey(filePath string, width, height, quality int) string {
-	data := fmt.Sprintf("%s_%d_%d_%d", filePath, width, height, quality)
+// GenerateCacheKey генерирует уникальный ключ для кэширования изображения.
+// version описывает состояние исходника (см. SourceMeta.Version): после замены файла
+// ключ меняется, и устаревшие варианты больше не отдаются даже из дискового кэша
+func GenerateCacheKey(filePath, version string, opts ProcessOptions) string {
+	data := fmt.Sprintf("%s_%s_%d_%d_%d_%s", filePath, version, opts.Width, opts.Height, opts.Quality, opts.Format)
+	// Режим вписывания, кадр-постер и наложения добавляются, только если заданы: ключи остальных вариантов не меняются
+	if opts.Fit != FitFill {
+		data += "_" + string(opts.Fit)
+	}
+	if opts.Poster {
+		data += "_poster"
+	}
+	if opts.Watermark != "" || opts.Text != "" {
+		data += "_wm:" + opts.Watermark + "_text:" + opts.Text
+	}
 	hash := md5.Sum([]byte(data))
 	return hex.EncodeToString(hash[:])
 }
 
-// GetCachedImage пытается получить изображение из кэша
-func GetCachedImage(filePath string, width, height, quality int) ([]byte, bool) {
-	if GlobalCache == nil {
-		return nil, false
-	}
-
-	key := GenerateCacheKey(filePath, width, height, quality)
-	return GlobalCache.Get(key)
-}
-
-// SetCachedImage сохраняет изображение в кэше
-func SetCachedImage(filePath string, width, height, quality int, imageData []byte) {
-	if GlobalCache == nil {
-		return
-	}
-
-	key := GenerateCacheKey(filePath, width, height, quality)
-	GlobalCache.Set(key, imageData)
-}
+// noCache заглушка для сервиса, созданного без кэша
+type noCache struct{}
 
-// InitializeCache инициализирует кэш при запуске приложения
-func InitializeCache() {
-	InitCache(1*time.Hour, 100) // TTL 1 час, максимум 100 изображений
-}
+func (noCache) Get(string) ([]byte, bool) { return nil, false }
+func (noCache) Set(string, []byte)        {}
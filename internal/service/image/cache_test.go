@@ -0,0 +1,205 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sameShardKeys подбирает n ключей, попадающих в один сегмент кэша
+func sameShardKeys(t *testing.T, c *ImageCache, n int) []string {
+	t.Helper()
+	target := c.shard("key-0")
+	keys := []string{"key-0"}
+	for i := 1; len(keys) < n; i++ {
+		if i > 100000 {
+			t.Fatalf("failed to find %d keys in one shard", n)
+		}
+		key := "key-" + strconv.Itoa(i)
+		if c.shard(key) == target {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func TestImageCacheGetSet(t *testing.T) {
+	c := NewImageCache(time.Hour, 1<<20)
+	defer func() {
+		_ = c.Close()
+	}()
+
+	if _, ok := c.Get("missing"); ok {
+		t.Fatal("Get(missing) = found, want miss")
+	}
+
+	c.Set("a", []byte("hello"))
+	data, ok := c.Get("a")
+	if !ok || string(data) != "hello" {
+		t.Fatalf("Get(a) = %q, %v; want \"hello\", true", data, ok)
+	}
+
+	// Повторный Set заменяет данные и не удваивает размер
+	c.Set("a", []byte("hi"))
+	data, _ = c.Get("a")
+	if string(data) != "hi" {
+		t.Fatalf("Get(a) after overwrite = %q, want \"hi\"", data)
+	}
+
+	stats := c.Stats()
+	want := CacheStats{Hits: 2, Misses: 1, Items: 1, Bytes: 2}
+	if stats != want {
+		t.Fatalf("Stats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestImageCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	// Бюджет сегмента 30 байт: помещаются ровно три элемента по 10 байт
+	c := NewImageCache(time.Hour, 30*cacheShards)
+	defer func() {
+		_ = c.Close()
+	}()
+
+	keys := sameShardKeys(t, c, 5)
+	item := bytes.Repeat([]byte("x"), 10)
+
+	c.Set(keys[0], item)
+	c.Set(keys[1], item)
+	c.Set(keys[2], item)
+
+	// Обращение делает keys[0] недавно использованным: первым уйдет keys[1]
+	if _, ok := c.Get(keys[0]); !ok {
+		t.Fatalf("Get(%s) = miss before eviction", keys[0])
+	}
+
+	c.Set(keys[3], item)
+	if _, ok := c.Get(keys[1]); ok {
+		t.Fatalf("Get(%s) = found, want evicted as least recently used", keys[1])
+	}
+
+	c.Set(keys[4], item)
+	if _, ok := c.Get(keys[2]); ok {
+		t.Fatalf("Get(%s) = found, want evicted as least recently used", keys[2])
+	}
+
+	for _, key := range []string{keys[0], keys[3], keys[4]} {
+		if _, ok := c.Get(key); !ok {
+			t.Fatalf("Get(%s) = miss, want kept", key)
+		}
+	}
+
+	stats := c.Stats()
+	if stats.Evictions != 2 || stats.Items != 3 || stats.Bytes != 30 {
+		t.Fatalf("Stats() = %+v, want 2 evictions, 3 items, 30 bytes", stats)
+	}
+}
+
+func TestImageCacheSkipsOversizedItem(t *testing.T) {
+	c := NewImageCache(time.Hour, 10*cacheShards)
+	defer func() {
+		_ = c.Close()
+	}()
+
+	c.Set("big", bytes.Repeat([]byte("x"), 11))
+	if _, ok := c.Get("big"); ok {
+		t.Fatal("Get(big) = found, want item larger than shard budget to be skipped")
+	}
+	if stats := c.Stats(); stats.Items != 0 || stats.Evictions != 0 {
+		t.Fatalf("Stats() = %+v, want empty cache without evictions", stats)
+	}
+}
+
+func TestImageCacheExpires(t *testing.T) {
+	c := NewImageCache(10*time.Millisecond, 1<<20)
+	defer func() {
+		_ = c.Close()
+	}()
+
+	c.Set("a", []byte("a"))
+	c.Set("b", []byte("b"))
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("Get(a) = found after TTL, want miss")
+	}
+
+	// Без обращения устаревший элемент удаляет очистка
+	c.cleanupExpired()
+	if stats := c.Stats(); stats.Items != 0 || stats.Bytes != 0 {
+		t.Fatalf("Stats() after cleanup = %+v, want empty cache", stats)
+	}
+}
+
+func TestImageCacheConcurrentAccess(t *testing.T) {
+	const (
+		workers = 16
+		rounds  = 500
+		keys    = 64
+		size    = 100
+	)
+	// Бюджет меньше, чем все ключи вместе, чтобы вытеснение шло под нагрузкой
+	c := NewImageCache(time.Hour, keys*size/2)
+	defer func() {
+		_ = c.Close()
+	}()
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rounds {
+				key := fmt.Sprintf("key-%d", (w*rounds+i)%keys)
+				if data, ok := c.Get(key); ok {
+					if len(data) != size || data[0] != key[len(key)-1] {
+						t.Errorf("Get(%s) returned foreign data", key)
+						return
+					}
+					continue
+				}
+				c.Set(key, bytes.Repeat([]byte{key[len(key)-1]}, size))
+				if i%50 == 0 {
+					_ = c.Stats()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := c.Stats()
+	if stats.Hits+stats.Misses != workers*rounds {
+		t.Fatalf("hits + misses = %d, want %d", stats.Hits+stats.Misses, workers*rounds)
+	}
+	if stats.Bytes > keys*size/2 {
+		t.Fatalf("Bytes = %d, exceeds budget %d", stats.Bytes, keys*size/2)
+	}
+	if stats.Bytes != stats.Items*size {
+		t.Fatalf("Bytes = %d, want Items*%d = %d", stats.Bytes, size, stats.Items*size)
+	}
+	if stats.Evictions == 0 {
+		t.Fatal("Evictions = 0, want eviction under concurrent load")
+	}
+}
+
+func TestImageCacheClose(t *testing.T) {
+	c := NewImageCache(time.Hour, 1<<20)
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+	// Повторный Close не паникует, а кэш продолжает отвечать
+	if err := c.Close(); err != nil {
+		t.Fatalf("second Close() = %v", err)
+	}
+	c.Set("a", []byte("a"))
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("Get(a) after Close = miss, want cache to keep working")
+	}
+
+	tiered := NewTieredCache(NewImageCache(time.Hour, 1<<20), noCache{})
+	if err := CloseCache(tiered); err != nil {
+		t.Fatalf("CloseCache(tiered) = %v", err)
+	}
+}
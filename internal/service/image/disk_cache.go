@@ -0,0 +1,242 @@
+package image
+
+import (
+	"container/list"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// diskTempPrefix префикс временных файлов; такие файлы - следы прерванной записи
+const diskTempPrefix = ".tmp-"
+
+// diskEntry запись индекса дискового кэша
+type diskEntry struct {
+	key  string
+	size int64
+}
+
+// DiskCache дисковый кэш обработанных изображений.
+// Файлы адресуются ключом кэша (<dir>/<2 символа ключа>/<ключ>), запись
+// атомарная (временный файл + rename), при превышении бюджета удаляются
+// давно не использованные файлы. Индекс держится в памяти и восстанавливается
+// при старте по содержимому каталога, поэтому кэш переживает перезапуски
+type DiskCache struct {
+	dir      string
+	maxBytes int64
+
+	mutex   sync.Mutex
+	size    int64
+	lru     *list.List // от недавно использованных к давно неиспользованным
+	entries map[string]*list.Element
+}
+
+// NewDiskCache создает дисковый кэш в каталоге dir с бюджетом maxBytes
+func NewDiskCache(dir string, maxBytes int64) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir: %w", err)
+	}
+
+	c := &DiskCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+
+	if err := c.rebuildIndex(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Get читает элемент с диска и отмечает его как недавно использованный
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	c.mutex.Lock()
+	elem, exists := c.entries[key]
+	if exists {
+		c.lru.MoveToFront(elem)
+	}
+	c.mutex.Unlock()
+
+	if !exists {
+		return nil, false
+	}
+
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// Файл удалили снаружи - забываем о нем. Если за это время Set записал
+		// ключ заново, в индексе уже другая запись, и ее файл трогать нельзя
+		c.mutex.Lock()
+		if c.entries[key] == elem {
+			c.forgetLocked(key)
+		}
+		c.mutex.Unlock()
+		return nil, false
+	}
+
+	// Время изменения файла хранит порядок LRU между перезапусками
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+
+	return data, true
+}
+
+// Set атомарно записывает элемент на диск и вытесняет старые при нехватке места.
+// Данные пишутся во временный файл без блокировки, а rename и запись в индекс
+// идут под мьютексом: иначе вытеснение прежней версии ключа из другого Set
+// могло бы удалить только что переименованный файл
+func (c *DiskCache) Set(key string, data []byte) {
+	size := int64(len(data))
+	if size > c.maxBytes {
+		return
+	}
+
+	path := c.path(key)
+	tmpName, err := writeTempFile(filepath.Dir(path), data)
+	if err != nil {
+		log.Printf("Disk cache write error: %v", err)
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := os.Rename(tmpName, path); err != nil {
+		_ = os.Remove(tmpName)
+		log.Printf("Disk cache write error: %v", err)
+		return
+	}
+
+	c.forgetLocked(key)
+	c.entries[key] = c.lru.PushFront(&diskEntry{key: key, size: size})
+	c.size += size
+
+	c.evictLocked()
+}
+
+// path возвращает путь к файлу элемента. Подкаталоги по первым символам ключа
+// не дают одному каталогу разрастись до сотен тысяч файлов
+func (c *DiskCache) path(key string) string {
+	if len(key) < 2 {
+		return filepath.Join(c.dir, key)
+	}
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// evictLocked удаляет давно не использованные элементы, пока размер не войдет в бюджет
+func (c *DiskCache) evictLocked() {
+	for c.size > c.maxBytes {
+		oldest := c.lru.Back()
+		if oldest == nil {
+			return
+		}
+		c.removeLocked(oldest.Value.(*diskEntry).key)
+	}
+}
+
+// removeLocked удаляет элемент из индекса и с диска
+func (c *DiskCache) removeLocked(key string) {
+	if !c.forgetLocked(key) {
+		return
+	}
+	if err := os.Remove(c.path(key)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Disk cache remove error: %v", err)
+	}
+}
+
+// forgetLocked удаляет элемент только из индекса
+func (c *DiskCache) forgetLocked(key string) bool {
+	elem, exists := c.entries[key]
+	if !exists {
+		return false
+	}
+	c.size -= elem.Value.(*diskEntry).size
+	c.lru.Remove(elem)
+	delete(c.entries, key)
+	return true
+}
+
+// rebuildIndex восстанавливает индекс по файлам в каталоге кэша.
+// Порядок LRU берется из времени изменения файлов, недописанные временные файлы удаляются
+func (c *DiskCache) rebuildIndex() error {
+	type found struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var files []found
+
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), diskTempPrefix) {
+			_ = os.Remove(path)
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files = append(files, found{key: d.Name(), size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan cache dir: %w", err)
+	}
+
+	// Сначала самые старые: каждый следующий PushFront делает файл "свежее"
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, f := range files {
+		c.entries[f.key] = c.lru.PushFront(&diskEntry{key: f.key, size: f.size})
+		c.size += f.size
+	}
+	c.evictLocked()
+
+	log.Printf("Disk image cache: %d files, %d bytes in %s", len(c.entries), c.size, c.dir)
+	return nil
+}
+
+// writeTempFile пишет данные во временный файл в каталоге dir и возвращает его
+// имя. Переименованием в итоговый путь занимается вызывающий: так читатели
+// никогда не видят наполовину записанное изображение
+func writeTempFile(dir string, data []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(dir, diskTempPrefix+"*")
+	if err != nil {
+		return "", err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return "", err
+	}
+	return tmpName, nil
+}
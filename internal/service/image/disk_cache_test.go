@@ -0,0 +1,218 @@
+package image
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestDiskCache создает дисковый кэш во временном каталоге
+func newTestDiskCache(t *testing.T, dir string, maxBytes int64) *DiskCache {
+	t.Helper()
+	c, err := NewDiskCache(dir, maxBytes)
+	if err != nil {
+		t.Fatalf("NewDiskCache: %v", err)
+	}
+	return c
+}
+
+// cacheFiles имена файлов в каталоге кэша
+func cacheFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var names []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			names = append(names, d.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk cache dir: %v", err)
+	}
+	return names
+}
+
+func TestDiskCacheRebuildsIndexFromModTimes(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestDiskCache(t, dir, 100)
+	for _, key := range []string{"aa01", "bb02", "cc03"} {
+		c.Set(key, bytes.Repeat([]byte{'x'}, 10))
+	}
+
+	// Порядок использования задается временем изменения: bb02 самый старый
+	base := time.Now().Add(-time.Hour)
+	for i, key := range []string{"bb02", "cc03", "aa01"} {
+		at := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(c.path(key), at, at); err != nil {
+			t.Fatalf("Chtimes: %v", err)
+		}
+	}
+	// След прерванной записи
+	tmp := filepath.Join(dir, "aa", diskTempPrefix+"123")
+	if err := os.WriteFile(tmp, []byte("partial"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	// Новый бюджет вмещает два файла: при восстановлении вытесняется самый старый
+	reopened := newTestDiskCache(t, dir, 25)
+	if reopened.size != 20 || len(reopened.entries) != 2 {
+		t.Fatalf("rebuilt index = %d files, %d bytes; want 2 files, 20 bytes", len(reopened.entries), reopened.size)
+	}
+	if _, found := reopened.Get("bb02"); found {
+		t.Error("oldest file bb02 survived the rebuild")
+	}
+	for _, key := range []string{"aa01", "cc03"} {
+		if data, found := reopened.Get(key); !found || len(data) != 10 {
+			t.Errorf("Get(%s) = %d bytes, %v; want 10 bytes", key, len(data), found)
+		}
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Errorf("temp file was not removed on rebuild: %v", err)
+	}
+}
+
+func TestDiskCacheEvictsLeastRecentlyUsedByBytes(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestDiskCache(t, dir, 30)
+
+	c.Set("aa01", bytes.Repeat([]byte{'a'}, 10))
+	c.Set("bb02", bytes.Repeat([]byte{'b'}, 10))
+	c.Set("cc03", bytes.Repeat([]byte{'c'}, 10))
+	c.Get("aa01")
+
+	// 15 байт не помещаются: уходят два давно не использованных файла, а не один
+	c.Set("dd04", bytes.Repeat([]byte{'d'}, 15))
+	if c.size != 25 {
+		t.Fatalf("size = %d, want 25", c.size)
+	}
+	for key, want := range map[string]bool{"aa01": true, "bb02": false, "cc03": false, "dd04": true} {
+		if _, found := c.Get(key); found != want {
+			t.Errorf("Get(%s) found = %v, want %v", key, found, want)
+		}
+		if _, err := os.Stat(c.path(key)); (err == nil) != want {
+			t.Errorf("file %s exists = %v, want %v", key, err == nil, want)
+		}
+	}
+
+	// Перезапись ключа не удваивает его размер, а слишком большой элемент не пишется
+	c.Set("aa01", bytes.Repeat([]byte{'a'}, 5))
+	c.Set("ee05", bytes.Repeat([]byte{'e'}, 31))
+	if c.size != 20 {
+		t.Fatalf("size after overwrite = %d, want 20", c.size)
+	}
+	if _, found := c.Get("ee05"); found {
+		t.Fatal("element larger than the budget was stored")
+	}
+}
+
+func TestDiskCacheWritesAtomically(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestDiskCache(t, dir, 1<<20)
+
+	c.Set("aa01", []byte("first"))
+	c.Set("aa01", []byte("second"))
+
+	data, err := os.ReadFile(c.path("aa01"))
+	if err != nil || string(data) != "second" {
+		t.Fatalf("file = %q, %v; want second version", data, err)
+	}
+	for _, name := range cacheFiles(t, dir) {
+		if strings.HasPrefix(name, diskTempPrefix) {
+			t.Errorf("temp file %s left after Set", name)
+		}
+	}
+}
+
+func TestDiskCacheConcurrentSetKeepsIndexAndFilesInSync(t *testing.T) {
+	dir := t.TempDir()
+	// Бюджет на три элемента: почти каждый Set вытесняет чужой ключ
+	c := newTestDiskCache(t, dir, 30)
+
+	var wg sync.WaitGroup
+	for worker := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				key := "k" + strconv.Itoa((worker+i)%5)
+				c.Set(key, bytes.Repeat([]byte{'x'}, 10))
+				c.Get(key)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Каждой записи индекса соответствует файл, и наоборот
+	files := cacheFiles(t, dir)
+	if len(files) != len(c.entries) {
+		t.Fatalf("%d files on disk, %d entries in index", len(files), len(c.entries))
+	}
+	var total int64
+	for key, elem := range c.entries {
+		info, err := os.Stat(c.path(key))
+		if err != nil {
+			t.Fatalf("indexed key %s has no file: %v", key, err)
+		}
+		total += info.Size()
+		if info.Size() != elem.Value.(*diskEntry).size {
+			t.Errorf("key %s: file %d bytes, index %d", key, info.Size(), elem.Value.(*diskEntry).size)
+		}
+	}
+	if total != c.size || c.size > c.maxBytes {
+		t.Fatalf("files take %d bytes, index says %d, budget %d", total, c.size, c.maxBytes)
+	}
+}
+
+func TestDiskCacheSetSurvivesEvictionOfPreviousVersion(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestDiskCache(t, dir, 100)
+	c.Set("aa01", []byte("old"))
+
+	// Пока мьютекс занят, Set новой версии может только подготовить файл
+	c.mutex.Lock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Set("aa01", []byte("new"))
+	}()
+	waitForDiskWrite(t, dir, c.path("aa01"))
+
+	// Другой Set вытесняет прежнюю версию ключа
+	c.removeLocked("aa01")
+	c.mutex.Unlock()
+	<-done
+
+	data, found := c.Get("aa01")
+	if !found || string(data) != "new" {
+		t.Fatalf("Get after concurrent eviction = %q, %v; want new version", data, found)
+	}
+}
+
+// waitForDiskWrite ждет, пока Set подготовит временный файл или переименует его в path
+func waitForDiskWrite(t *testing.T, dir, path string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if data, err := os.ReadFile(path); err == nil && string(data) == "new" {
+			return
+		}
+		for _, name := range cacheFiles(t, dir) {
+			if strings.HasPrefix(name, diskTempPrefix) {
+				// Даем записи шанс дойти до rename, если он идет без мьютекса
+				time.Sleep(20 * time.Millisecond)
+				return
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("Set did not write the file")
+}
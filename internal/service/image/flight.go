@@ -0,0 +1,81 @@
+package image
+
+import (
+	"context"
+	"sync"
+)
+
+// flightCall одна выполняемая обработка, которую ждут один или несколько запросов
+type flightCall struct {
+	done    chan struct{}
+	data    []byte
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// flightGroup объединяет одновременные запросы с одинаковым ключом: работу
+// выполняет одна горутина, остальные ждут ее результат. В отличие от
+// x/sync/singleflight ожидание учитывает контекст каждого запроса, а сама
+// работа отменяется, только когда ушли все ожидающие или отменен base
+type flightGroup struct {
+	mutex sync.Mutex
+	calls map[string]*flightCall
+	// base контекст приложения: его отмена (остановка сервера) прерывает всю работу; nil - не прерывается
+	base context.Context
+}
+
+// Do выполняет fn один раз для всех одновременных вызовов с ключом key
+func (g *flightGroup) Do(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	g.mutex.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+
+	call, exists := g.calls[key]
+	if !exists {
+		// Работа не должна прерываться, если отключился только первый клиент,
+		// но останавливается вместе с приложением
+		workCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		stopBase := func() bool { return false }
+		if g.base != nil {
+			stopBase = context.AfterFunc(g.base, cancel)
+		}
+		call = &flightCall{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = call
+
+		go func() {
+			call.data, call.err = fn(workCtx)
+			stopBase()
+
+			g.mutex.Lock()
+			if g.calls[key] == call {
+				delete(g.calls, key)
+			}
+			g.mutex.Unlock()
+
+			cancel()
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	g.mutex.Unlock()
+
+	select {
+	case <-call.done:
+		return call.data, call.err
+	case <-ctx.Done():
+		g.mutex.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			// Результат больше никому не нужен: отменяем работу, а новые
+			// запросы с этим ключом начнут обработку заново
+			call.cancel()
+			if g.calls[key] == call {
+				delete(g.calls, key)
+			}
+		}
+		g.mutex.Unlock()
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,97 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingWork ждет отмены контекста и сообщает о старте
+func blockingWork(started chan<- struct{}) func(ctx context.Context) ([]byte, error) {
+	return func(ctx context.Context) ([]byte, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+}
+
+func TestFlightGroupCancelledWithBase(t *testing.T) {
+	base, cancelBase := context.WithCancel(context.Background())
+	g := flightGroup{base: base}
+
+	started := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		_, err := g.Do(context.Background(), "key", blockingWork(started))
+		result <- err
+	}()
+	<-started
+
+	// Запрос жив, но приложение останавливается: работа прерывается
+	cancelBase()
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Do() = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("work kept running after base context was cancelled")
+	}
+}
+
+func TestFlightGroupSurvivesFirstWaiterLeaving(t *testing.T) {
+	g := flightGroup{base: context.Background()}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	work := func(ctx context.Context) ([]byte, error) {
+		close(started)
+		select {
+		case <-release:
+			return []byte("done"), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	firstResult := make(chan error, 1)
+	go func() {
+		_, err := g.Do(first, "key", work)
+		firstResult <- err
+	}()
+	<-started
+
+	secondResult := make(chan []byte, 1)
+	go func() {
+		data, _ := g.Do(context.Background(), "key", work)
+		secondResult <- data
+	}()
+
+	// Ждем, пока второй запрос присоединится к работе
+	for {
+		g.mutex.Lock()
+		waiters := g.calls["key"].waiters
+		g.mutex.Unlock()
+		if waiters == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancelFirst()
+	if err := <-firstResult; !errors.Is(err, context.Canceled) {
+		t.Fatalf("first Do() = %v, want context.Canceled", err)
+	}
+
+	close(release)
+	select {
+	case data := <-secondResult:
+		if string(data) != "done" {
+			t.Fatalf("second Do() = %q, want \"done\"", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second waiter did not get the result")
+	}
+}
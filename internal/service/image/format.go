@@ -0,0 +1,141 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gen2brain/avif"
+	"github.com/gen2brain/webp"
+)
+
+// Format формат, в который кодируется обработанное изображение
+type Format string
+
+const (
+	// FormatDefault формат не задан: JPEG, а анимированный GIF остается GIF,
+	// чтобы не потерять анимацию. Конкретный формат выбирается по исходнику
+	FormatDefault Format = ""
+	FormatJPEG    Format = "jpeg"
+	FormatPNG     Format = "png"
+	FormatGIF     Format = "gif"
+	FormatWebP    Format = "webp"
+	FormatAVIF    Format = "avif"
+	// FormatAuto формат выбирается по заголовку Accept (см. AcceptedFormat и
+	// ProcessorService.NegotiateFormat). Без запроса, например при прогреве, - как FormatDefault
+	FormatAuto Format = "auto"
+)
+
+// PictureFormats форматы, которые шаблоны предлагают в <picture> в дополнение
+// к формату по умолчанию, от лучшего сжатия к худшему
+var PictureFormats = []Format{FormatAVIF, FormatWebP}
+
+// avifSpeed скорость кодировщика AVIF (0-10): 8 сжимает почти так же, как
+// медленные настройки, но укладывается в сотни миллисекунд на вариант
+const avifSpeed = 8
+
+// EncodeFunc кодирует изображение в конкретный формат с заданным качеством
+type EncodeFunc func(w io.Writer, img image.Image, quality int) error
+
+// encoders форматы, которые умеем кодировать. WebP и AVIF кодируются через
+// libwebp и libavif, собранные в WebAssembly (gen2brain/webp, gen2brain/avif):
+// cgo не нужен, но первый вызов каждого кодировщика компилирует модуль и
+// занимает до пары секунд
+var encoders = map[Format]EncodeFunc{
+	FormatJPEG: func(w io.Writer, img image.Image, quality int) error {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	},
+	FormatPNG: func(w io.Writer, img image.Image, _ int) error {
+		return png.Encode(w, img)
+	},
+	FormatGIF: func(w io.Writer, img image.Image, _ int) error {
+		return gif.Encode(w, img, nil)
+	},
+	FormatWebP: func(w io.Writer, img image.Image, quality int) error {
+		return webp.Encode(w, img, webp.Options{Quality: quality})
+	},
+	FormatAVIF: func(w io.Writer, img image.Image, quality int) error {
+		return avif.Encode(w, img, avif.Options{Quality: quality, QualityAlpha: quality, Speed: avifSpeed})
+	},
+}
+
+// ParseFormat разбирает значение параметра fm. Пустая строка - FormatDefault
+func ParseFormat(value string) (Format, error) {
+	switch strings.ToLower(value) {
+	case "":
+		return FormatDefault, nil
+	case "jpg", "jpeg":
+		return FormatJPEG, nil
+	case "png":
+		return FormatPNG, nil
+	case "gif":
+		return FormatGIF, nil
+	case "webp":
+		return FormatWebP, nil
+	case "avif":
+		return FormatAVIF, nil
+	case "auto":
+		return FormatAuto, nil
+	default:
+		return "", fmt.Errorf("unknown image format: %s", value)
+	}
+}
+
+// ContentType возвращает MIME-тип формата
+func (f Format) ContentType() string {
+	return "image/" + string(f)
+}
+
+// IsSupported сообщает, можно ли получить вариант в этом формате: есть
+// кодировщик или формат выбирается по исходнику и запросу (FormatDefault, FormatAuto)
+func (f Format) IsSupported() bool {
+	_, ok := encoders[f]
+	return ok || f == FormatDefault || f == FormatAuto
+}
+
+// AcceptedFormat выбирает лучший из PictureFormats, который клиент перечислил
+// в заголовке Accept, иначе FormatDefault. Маски image/* и */* не в счет:
+// браузеры, которые понимают AVIF и WebP, называют их явно
+func AcceptedFormat(accept string) Format {
+	accepted := make(map[string]bool)
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(mediaRange, ";")
+		if rejectedByQuality(params) {
+			continue
+		}
+		accepted[strings.ToLower(strings.TrimSpace(mediaType))] = true
+	}
+
+	for _, format := range PictureFormats {
+		if accepted[format.ContentType()] {
+			return format
+		}
+	}
+	return FormatDefault
+}
+
+// rejectedByQuality проверяет параметры диапазона Accept на q=0 ("не присылать")
+func rejectedByQuality(params string) bool {
+	for _, param := range strings.Split(params, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if strings.EqualFold(name, "q") {
+			q, err := strconv.ParseFloat(value, 64)
+			return err == nil && q == 0
+		}
+	}
+	return false
+}
+
+// encoder возвращает кодировщик формата
+func (f Format) encoder() (EncodeFunc, error) {
+	fn, ok := encoders[f]
+	if !ok {
+		return nil, fmt.Errorf("image format %s is not supported", f)
+	}
+	return fn, nil
+}
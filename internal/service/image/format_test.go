@@ -0,0 +1,98 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := map[string]Format{
+		"":     FormatDefault,
+		"jpg":  FormatJPEG,
+		"JPEG": FormatJPEG,
+		"png":  FormatPNG,
+		"gif":  FormatGIF,
+		"webp": FormatWebP,
+		"AVIF": FormatAVIF,
+		"auto": FormatAuto,
+	}
+	for value, want := range tests {
+		format, err := ParseFormat(value)
+		if err != nil || format != want || !format.IsSupported() {
+			t.Errorf("ParseFormat(%q) = %q, %v; want supported %q", value, format, err, want)
+		}
+	}
+}
+
+func TestParseFormatRejectsUnencodableFormats(t *testing.T) {
+	for _, value := range []string{"bmp", "heic"} {
+		if _, err := ParseFormat(value); err == nil {
+			t.Errorf("ParseFormat(%q) = nil error, want unsupported format", value)
+		}
+	}
+}
+
+func TestAcceptedFormat(t *testing.T) {
+	tests := map[string]Format{
+		"":                                  FormatDefault,
+		"image/*,*/*;q=0.8":                 FormatDefault,
+		"image/webp,image/*,*/*;q=0.8":      FormatWebP,
+		"image/avif,image/webp,*/*":         FormatAVIF,
+		"image/webp, image/AVIF;q=0.5":      FormatAVIF,
+		"image/avif;q=0, image/webp;q=0.9":  FormatWebP,
+		"image/avif; q=0.0, image/png, */*": FormatDefault,
+		"text/html,application/xhtml+xml":   FormatDefault,
+	}
+	for accept, want := range tests {
+		if got := AcceptedFormat(accept); got != want {
+			t.Errorf("AcceptedFormat(%q) = %q, want %q", accept, got, want)
+		}
+	}
+}
+
+func TestNegotiateFormatKeepsAnimation(t *testing.T) {
+	ps := NewProcessorService(ProcessorOptions{Media: fstest.MapFS{
+		"images/anim.gif": {Data: testAnimatedGIF(t)},
+		"images/a.png":    {Data: testPNG(t, 20, 10)},
+	}})
+	ctx := context.Background()
+	accept := "image/avif,image/webp,*/*"
+
+	tests := []struct {
+		path string
+		opts ProcessOptions
+		want Format
+	}{
+		{"/static/images/a.png", ProcessOptions{}, FormatAVIF},
+		{"/static/images/anim.gif", ProcessOptions{}, FormatDefault},
+		{"/static/images/anim.gif", ProcessOptions{Poster: true}, FormatAVIF},
+	}
+	for _, tt := range tests {
+		format, err := ps.NegotiateFormat(ctx, tt.path, tt.opts, accept)
+		if err != nil || format != tt.want {
+			t.Errorf("NegotiateFormat(%s, poster=%v) = %q, %v; want %q", tt.path, tt.opts.Poster, format, err, tt.want)
+		}
+	}
+}
+
+func TestEncodersProduceDecodableImages(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for format := range encoders {
+		encode, err := format.encoder()
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		var buf bytes.Buffer
+		if err := encode(&buf, src, DefaultQuality); err != nil {
+			t.Fatalf("encode %s: %v", format, err)
+		}
+
+		cfg, name, err := image.DecodeConfig(&buf)
+		if err != nil || name != string(format) || cfg.Width != 16 || cfg.Height != 8 {
+			t.Errorf("decode %s = %s %dx%d, %v; want %s 16x8", format, name, cfg.Width, cfg.Height, err, format)
+		}
+	}
+}
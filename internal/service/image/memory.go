@@ -0,0 +1,148 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrProcessingTimeout обработка не уложилась в ProcessorOptions.ProcessTimeout
+var ErrProcessingTimeout = errors.New("image processing timed out")
+
+// memoryBudget учитывает оценку памяти, занятой идущими обработками.
+// Обработка, которой не хватает бюджета, ждет, пока другие освободят память
+type memoryBudget struct {
+	limit int64 // 0 - без ограничения
+
+	mutex sync.Mutex
+	used  int64
+	freed chan struct{} // закрывается и пересоздается при каждом освобождении
+}
+
+// newMemoryBudget создает бюджет на limit байт
+func newMemoryBudget(limit int64) *memoryBudget {
+	return &memoryBudget{limit: limit, freed: make(chan struct{})}
+}
+
+// reserve занимает n байт бюджета. Запрос больше всего бюджета отклоняется сразу
+// с ErrImageTooLarge: дождаться его невозможно. Если память не освободилась за
+// timeout, возвращается ErrBusy
+func (b *memoryBudget) reserve(ctx context.Context, n int64, timeout time.Duration) (func(), error) {
+	if b.limit <= 0 {
+		return func() {}, nil
+	}
+	if n > b.limit {
+		return nil, fmt.Errorf("%w: needs about %d MB of memory, budget is %d MB", ErrImageTooLarge, n>>20, b.limit>>20)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		b.mutex.Lock()
+		if b.used+n <= b.limit {
+			b.used += n
+			b.mutex.Unlock()
+			return func() { b.release(n) }, nil
+		}
+		freed := b.freed
+		b.mutex.Unlock()
+
+		select {
+		case <-freed:
+		case <-timer.C:
+			return nil, ErrBusy
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// release возвращает n байт в бюджет и будит ожидающих
+func (b *memoryBudget) release(n int64) {
+	b.mutex.Lock()
+	b.used -= n
+	close(b.freed)
+	b.freed = make(chan struct{})
+	b.mutex.Unlock()
+}
+
+// estimateMemory оценивает с запасом память на обработку одного варианта:
+// декодированный исходник, рабочая копия в NRGBA, которую делает ресайз,
+// и два растра результата (ресайз и наложения)
+func estimateMemory(cfg image.Config, opts ProcessOptions) int64 {
+	source := int64(cfg.Width) * int64(cfg.Height)
+	return source*decodedBytesPerPixel(cfg.ColorModel) + source*4 + 2*4*outputPixels(cfg.Width, cfg.Height, opts)
+}
+
+// estimateAnimationMemory оценивает память на покадровую обработку GIF:
+// декодированные кадры (байт на пиксель), холст с копией для DisposalPrevious
+// и кадры результата в NRGBA и в палитре
+func estimateAnimationMemory(width, height, frames int, framePixels int64, opts ProcessOptions) int64 {
+	canvas := int64(width) * int64(height)
+	output := outputPixels(width, height, opts)
+	return framePixels + 2*4*canvas + int64(frames)*5*output
+}
+
+// decodedBytesPerPixel размер пикселя декодированного изображения по его цветовой модели
+func decodedBytesPerPixel(model color.Model) int64 {
+	switch model {
+	case color.GrayModel, color.AlphaModel:
+		return 1
+	case color.Gray16Model, color.Alpha16Model:
+		return 2
+	case color.RGBA64Model, color.NRGBA64Model:
+		return 8
+	}
+	if _, ok := model.(color.Palette); ok {
+		return 1
+	}
+	// RGBA, NRGBA и YCbCr без прореживания цветности; JPEG с прореживанием меньше
+	return 4
+}
+
+// outputPixels площадь результата ресайза (см. resizeImage)
+func outputPixels(width, height int, opts ProcessOptions) int64 {
+	switch {
+	case opts.Width > 0 && opts.Height > 0:
+		return int64(opts.Width) * int64(opts.Height)
+	case opts.Width > 0 && width > 0:
+		return int64(opts.Width) * max(1, int64(opts.Width)*int64(height)/int64(width))
+	case opts.Height > 0 && height > 0:
+		return int64(opts.Height) * max(1, int64(opts.Height)*int64(width)/int64(height))
+	default:
+		return int64(width) * int64(height)
+	}
+}
+
+// contextReader прерывает чтение при отмене контекста. Декодеры читают
+// поблочно, поэтому отмена останавливает декодирование большого файла на ходу
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// contextWriter прерывает кодирование при отмене контекста
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (cw contextWriter) Write(p []byte) (int, error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(p)
+}
@@ -0,0 +1,425 @@
+package image
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Position положение наложения на изображении
+type Position string
+
+const (
+	PositionTopLeft     Position = "top-left"
+	PositionTop         Position = "top"
+	PositionTopRight    Position = "top-right"
+	PositionLeft        Position = "left"
+	PositionCenter      Position = "center"
+	PositionRight       Position = "right"
+	PositionBottomLeft  Position = "bottom-left"
+	PositionBottom      Position = "bottom"
+	PositionBottomRight Position = "bottom-right"
+)
+
+// overlayNamePattern допустимые имена наложений (как у пресетов)
+var overlayNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Watermark водяной знак: картинка из файла поверх изображения
+type Watermark struct {
+	Name     string
+	Position Position
+	Opacity  float64 // 0-1
+	Scale    float64 // ширина знака относительно ширины изображения, 0-1
+	Margin   float64 // отступ от края относительно меньшей стороны изображения
+	Tile     bool    // повторять знак по всему изображению вместо одного положения
+
+	image image.Image
+}
+
+// TextOverlay текстовая надпись поверх изображения, шрифт Go Bold встроен в бинарник
+type TextOverlay struct {
+	Name     string
+	Text     string
+	Position Position
+	Opacity  float64
+	Size     float64 // высота шрифта относительно меньшей стороны изображения
+	Margin   float64
+	Color    color.NRGBA
+}
+
+// Overlays водяные знаки и надписи, на которые ссылаются пресеты (wm:имя, text:имя)
+type Overlays struct {
+	watermarks map[string]*Watermark
+	texts      map[string]*TextOverlay
+	// fingerprints отпечатки определений и файлов: входят в ключ кэша, чтобы
+	// смена картинки знака или текста надписи сразу давала новые варианты
+	fingerprints map[string]string
+	font         *opentype.Font
+}
+
+// NewOverlays разбирает наложения из строк конфига в том же формате, что и пресеты:
+//
+//	brand=file:./assets/logo.png,pos:bottom-right,opacity:0.6,scale:0.2
+//	copyright=text:© Example,pos:bottom-left,size:0.04,color:ffffff
+//
+// Текст надписи не может содержать "," и ";" - это разделители конфига
+func NewOverlays(watermarkSpec, textSpec string) (*Overlays, error) {
+	ttf, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse embedded font: %w", err)
+	}
+
+	overlays := &Overlays{
+		watermarks:   make(map[string]*Watermark),
+		texts:        make(map[string]*TextOverlay),
+		fingerprints: make(map[string]string),
+		font:         ttf,
+	}
+
+	err = eachOverlaySpec(watermarkSpec, func(name, params string) error {
+		wm, data, err := parseWatermark(name, params)
+		if err != nil {
+			return err
+		}
+		overlays.watermarks[name] = wm
+		overlays.fingerprints["wm:"+name] = fingerprint(params, data)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("watermark %w", err)
+	}
+
+	err = eachOverlaySpec(textSpec, func(name, params string) error {
+		text, err := parseTextOverlay(name, params)
+		if err != nil {
+			return err
+		}
+		overlays.texts[name] = text
+		overlays.fingerprints["text:"+name] = fingerprint(params, nil)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("text overlay %w", err)
+	}
+
+	return overlays, nil
+}
+
+// HasWatermark сообщает, определен ли водяной знак с таким именем
+func (o *Overlays) HasWatermark(name string) bool {
+	_, ok := o.watermarks[name]
+	return ok
+}
+
+// HasText сообщает, определена ли надпись с таким именем
+func (o *Overlays) HasText(name string) bool {
+	_, ok := o.texts[name]
+	return ok
+}
+
+// Fingerprint отпечаток наложений варианта для ключа кэша; пусто, если их нет
+func (o *Overlays) Fingerprint(opts ProcessOptions) string {
+	if o == nil || (opts.Watermark == "" && opts.Text == "") {
+		return ""
+	}
+	return o.fingerprints["wm:"+opts.Watermark] + o.fingerprints["text:"+opts.Text]
+}
+
+// Apply накладывает водяной знак и надпись варианта. Без наложений
+// возвращает изображение как есть
+func (o *Overlays) Apply(img image.Image, opts ProcessOptions) image.Image {
+	if o == nil || (opts.Watermark == "" && opts.Text == "") {
+		return img
+	}
+
+	dst := imaging.Clone(img)
+	if wm, ok := o.watermarks[opts.Watermark]; ok {
+		o.drawWatermark(dst, wm)
+	}
+	if text, ok := o.texts[opts.Text]; ok {
+		o.drawText(dst, text)
+	}
+	return dst
+}
+
+// drawWatermark рисует водяной знак
+func (o *Overlays) drawWatermark(dst *image.NRGBA, wm *Watermark) {
+	bounds := dst.Bounds()
+	width := max(1, int(float64(bounds.Dx())*wm.Scale))
+	mark := imaging.Resize(wm.image, width, 0, imaging.Lanczos)
+	mask := image.NewUniform(color.Alpha{A: uint8(wm.Opacity * 255)})
+	margin := int(float64(min(bounds.Dx(), bounds.Dy())) * wm.Margin)
+
+	if !wm.Tile {
+		at := place(bounds, mark.Bounds().Size(), wm.Position, margin)
+		draw.DrawMask(dst, mark.Bounds().Add(at), mark, image.Point{}, mask, image.Point{}, draw.Over)
+		return
+	}
+
+	// Плитка: знаки с шагом в размер знака плюс отступ, ряды со сдвигом на полшага
+	step := mark.Bounds().Size().Add(image.Pt(max(margin, 1), max(margin, 1)))
+	for row, y := 0, bounds.Min.Y; y < bounds.Max.Y; row, y = row+1, y+step.Y {
+		x := bounds.Min.X
+		if row%2 == 1 {
+			x -= step.X / 2
+		}
+		for ; x < bounds.Max.X; x += step.X {
+			r := mark.Bounds().Add(image.Pt(x, y))
+			draw.DrawMask(dst, r, mark, image.Point{}, mask, image.Point{}, draw.Over)
+		}
+	}
+}
+
+// drawText рисует надпись с легкой тенью, чтобы она читалась на любом фоне
+func (o *Overlays) drawText(dst *image.NRGBA, text *TextOverlay) {
+	bounds := dst.Bounds()
+	shortSide := float64(min(bounds.Dx(), bounds.Dy()))
+	size := max(8, shortSide*text.Size)
+
+	face, err := opentype.NewFace(o.font, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = face.Close()
+	}()
+
+	metrics := face.Metrics()
+	textSize := image.Pt(
+		font.MeasureString(face, text.Text).Ceil(),
+		(metrics.Ascent + metrics.Descent).Ceil(),
+	)
+	at := place(bounds, textSize, text.Position, int(shortSide*text.Margin))
+	baseline := fixed.P(at.X, at.Y+metrics.Ascent.Ceil())
+
+	shadowOffset := fixed.I(max(1, int(size/20)))
+	shadow := color.NRGBA{A: uint8(text.Opacity * 128)}
+	fill := text.Color
+	fill.A = uint8(float64(fill.A) * text.Opacity)
+
+	drawer := &font.Drawer{Dst: dst, Face: face}
+	drawer.Src = image.NewUniform(shadow)
+	drawer.Dot = baseline.Add(fixed.Point26_6{X: shadowOffset, Y: shadowOffset})
+	drawer.DrawString(text.Text)
+
+	drawer.Src = image.NewUniform(fill)
+	drawer.Dot = baseline
+	drawer.DrawString(text.Text)
+}
+
+// place вычисляет левый верхний угол прямоугольника size в положении pos с отступом margin
+func place(bounds image.Rectangle, size image.Point, pos Position, margin int) image.Point {
+	left := bounds.Min.X + margin
+	centerX := bounds.Min.X + (bounds.Dx()-size.X)/2
+	right := bounds.Max.X - size.X - margin
+	top := bounds.Min.Y + margin
+	centerY := bounds.Min.Y + (bounds.Dy()-size.Y)/2
+	bottom := bounds.Max.Y - size.Y - margin
+
+	switch pos {
+	case PositionTopLeft:
+		return image.Pt(left, top)
+	case PositionTop:
+		return image.Pt(centerX, top)
+	case PositionTopRight:
+		return image.Pt(right, top)
+	case PositionLeft:
+		return image.Pt(left, centerY)
+	case PositionCenter:
+		return image.Pt(centerX, centerY)
+	case PositionRight:
+		return image.Pt(right, centerY)
+	case PositionBottomLeft:
+		return image.Pt(left, bottom)
+	case PositionBottom:
+		return image.Pt(centerX, bottom)
+	default:
+		return image.Pt(right, bottom)
+	}
+}
+
+// eachOverlaySpec разбирает список "имя=параметры; имя=параметры"
+func eachOverlaySpec(spec string, fn func(name, params string) error) error {
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, params, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !ok {
+			return fmt.Errorf("%q: expected name=params", item)
+		}
+		if !overlayNamePattern.MatchString(name) {
+			return fmt.Errorf("%q: invalid name", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("%q: defined twice", name)
+		}
+		seen[name] = true
+		if err := fn(name, params); err != nil {
+			return fmt.Errorf("%q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// parseWatermark разбирает параметры водяного знака и загружает файл
+func parseWatermark(name, params string) (*Watermark, []byte, error) {
+	wm := &Watermark{Name: name, Position: PositionBottomRight, Opacity: 0.5, Scale: 0.2, Margin: 0.03}
+	var file string
+
+	err := eachParam(params, func(key, value string) error {
+		var err error
+		switch key {
+		case "file":
+			file = value
+		case "pos":
+			wm.Position, err = parsePosition(value)
+		case "opacity":
+			wm.Opacity, err = parseFraction(value)
+		case "scale":
+			wm.Scale, err = parseFraction(value)
+		case "margin":
+			wm.Margin, err = parseFraction(value)
+		case "tile":
+			wm.Tile, err = strconv.ParseBool(value)
+		default:
+			err = fmt.Errorf("unknown parameter %q", key)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if file == "" {
+		return nil, nil, fmt.Errorf("file is required")
+	}
+	if wm.Scale == 0 {
+		return nil, nil, fmt.Errorf("scale must be greater than 0")
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	wm.image, _, err = image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode %s: %w", file, err)
+	}
+	return wm, data, nil
+}
+
+// parseTextOverlay разбирает параметры надписи
+func parseTextOverlay(name, params string) (*TextOverlay, error) {
+	text := &TextOverlay{
+		Name:     name,
+		Position: PositionBottomLeft,
+		Opacity:  0.8,
+		Size:     0.05,
+		Margin:   0.03,
+		Color:    color.NRGBA{R: 255, G: 255, B: 255, A: 255},
+	}
+
+	err := eachParam(params, func(key, value string) error {
+		var err error
+		switch key {
+		case "text":
+			text.Text = value
+		case "pos":
+			text.Position, err = parsePosition(value)
+		case "opacity":
+			text.Opacity, err = parseFraction(value)
+		case "size":
+			text.Size, err = parseFraction(value)
+		case "margin":
+			text.Margin, err = parseFraction(value)
+		case "color":
+			text.Color, err = parseHexColor(value)
+		default:
+			err = fmt.Errorf("unknown parameter %q", key)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if text.Text == "" {
+		return nil, fmt.Errorf("text is required")
+	}
+	return text, nil
+}
+
+// eachParam разбирает параметры "ключ:значение" через запятую
+func eachParam(params string, fn func(key, value string) error) error {
+	for _, param := range strings.Split(params, ",") {
+		param = strings.TrimSpace(param)
+		if param == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(param, ":")
+		if !ok {
+			return fmt.Errorf("expected key:value, got %q", param)
+		}
+		if err := fn(strings.TrimSpace(key), strings.TrimSpace(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parsePosition разбирает положение наложения
+func parsePosition(value string) (Position, error) {
+	switch pos := Position(value); pos {
+	case PositionTopLeft, PositionTop, PositionTopRight, PositionLeft, PositionCenter,
+		PositionRight, PositionBottomLeft, PositionBottom, PositionBottomRight:
+		return pos, nil
+	default:
+		return "", fmt.Errorf("unknown position %q", value)
+	}
+}
+
+// parseFraction разбирает число от 0 до 1
+func parseFraction(value string) (float64, error) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 || f > 1 {
+		return 0, fmt.Errorf("expected a number from 0 to 1, got %q", value)
+	}
+	return f, nil
+}
+
+// parseHexColor разбирает цвет вида ffffff или ffffff80 (с альфой), # необязателен
+func parseHexColor(value string) (color.NRGBA, error) {
+	value = strings.TrimPrefix(value, "#")
+	if len(value) == 6 {
+		value += "ff"
+	}
+	raw, err := hex.DecodeString(value)
+	if err != nil || len(raw) != 4 {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q", value)
+	}
+	return color.NRGBA{R: raw[0], G: raw[1], B: raw[2], A: raw[3]}, nil
+}
+
+// fingerprint короткий отпечаток определения наложения и его файла
+func fingerprint(params string, data []byte) string {
+	h := md5.New()
+	h.Write([]byte(params))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))[:8]
+}
@@ -0,0 +1,111 @@
+package image
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	// placeholderWidth ширина LQIP-превью: браузер все равно растягивает его
+	// на весь блок, а data-URI остается меньше килобайта
+	placeholderWidth   = 16
+	placeholderQuality = 40
+
+	// blurHashSampleWidth ширина, до которой уменьшаем картинку перед BlurHash:
+	// больше деталей хэшу не нужно, а кодирование квадратично по площади
+	blurHashSampleWidth = 32
+)
+
+// Placeholder превью изображения, которое показывается до загрузки полной картинки
+type Placeholder struct {
+	DataURI  string `json:"data_uri"`
+	BlurHash string `json:"blurhash"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+}
+
+// Placeholder возвращает превью изображения по публичному пути (/static/...).
+// Результат кэшируется рядом с обработанными вариантами; версия исходника
+// входит в ключ, поэтому замена исходника сразу дает новое превью
+func (ps *ProcessorService) Placeholder(ctx context.Context, path string) (*Placeholder, error) {
+	ctx = WithOriginMemo(ctx)
+	src, err := ps.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	meta, err := src.stat(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheKey := GenerateCacheKey("placeholder:"+src.name, meta.Version, ProcessOptions{})
+	if data, found := ps.cache.Get(cacheKey); found {
+		var cached Placeholder
+		if err := json.Unmarshal(data, &cached); err == nil {
+			return &cached, nil
+		}
+	}
+
+	data, err := ps.flights.Do(ctx, cacheKey, func(ctx context.Context) ([]byte, error) {
+		release, err := ps.acquireWorker(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		img, releaseMemory, err := ps.openImage(ctx, src, ProcessOptions{Width: placeholderWidth})
+		if err != nil {
+			return nil, err
+		}
+		defer releaseMemory()
+
+		placeholder, err := ps.generatePlaceholder(ctx, img)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(placeholder)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var placeholder Placeholder
+	if err := json.Unmarshal(data, &placeholder); err != nil {
+		return nil, err
+	}
+
+	ps.cache.Set(cacheKey, data)
+
+	return &placeholder, nil
+}
+
+// generatePlaceholder строит data-URI и BlurHash из декодированного изображения
+func (ps *ProcessorService) generatePlaceholder(ctx context.Context, src image.Image) (*Placeholder, error) {
+	bounds := src.Bounds()
+
+	small := imaging.Blur(imaging.Resize(src, placeholderWidth, 0, imaging.Box), 1)
+	data, err := ps.encodeOptimizedImage(ctx, small, FormatJPEG, placeholderQuality)
+	if err != nil {
+		return nil, err
+	}
+
+	sample := imaging.Resize(src, blurHashSampleWidth, 0, imaging.Box)
+
+	return &Placeholder{
+		DataURI:  "data:" + FormatJPEG.ContentType() + ";base64," + base64.StdEncoding.EncodeToString(data),
+		BlurHash: EncodeBlurHash(sample),
+		Width:    bounds.Dx(),
+		Height:   bounds.Dy(),
+	}, nil
+}
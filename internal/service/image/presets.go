@@ -0,0 +1,207 @@
+package image
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxPresetDimension предельная ширина и высота в пресете
+const maxPresetDimension = 8192
+
+// presetNamePattern допустимые имена пресетов: они входят в URL (/img/{preset}/...)
+var presetNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Preset именованный набор параметров обработки
+type Preset struct {
+	Name    string `json:"name"`
+	Width   int    `json:"w,omitempty"`
+	Height  int    `json:"h,omitempty"`
+	Quality int    `json:"q"`
+	Fit     Fit    `json:"fit,omitempty"`
+	Format  Format `json:"fm,omitempty"` // пусто - FormatDefault
+	Poster  bool   `json:"poster,omitempty"`
+	// Watermark и Text имена наложений из Overlays
+	Watermark string `json:"wm,omitempty"`
+	Text      string `json:"text,omitempty"`
+}
+
+// Options возвращает параметры обработки пресета
+func (p Preset) Options() ProcessOptions {
+	return ProcessOptions{
+		Width:     p.Width,
+		Height:    p.Height,
+		Quality:   p.Quality,
+		Format:    p.Format,
+		Fit:       p.Fit,
+		Poster:    p.Poster,
+		Watermark: p.Watermark,
+		Text:      p.Text,
+	}
+}
+
+// Presets набор пресетов, проверенный при старте
+type Presets struct {
+	byName map[string]Preset
+	list   []Preset // отсортирован по имени
+}
+
+// ParsePresets разбирает пресеты из строки конфига: пресеты через ";",
+// параметры через ",", например
+//
+//	thumb=w:150,h:150,fit:cover,q:75; hero=w:1200,fm:png,wm:brand,text:copyright
+//
+// Неизвестные параметры, недопустимые значения, повторяющиеся имена и ссылки
+// на несуществующие наложения - ошибка: опечатка в конфиге должна остановить
+// старт, а не тихо менять картинки. overlays может быть nil, если наложений нет
+func ParsePresets(spec string, overlays *Overlays) (*Presets, error) {
+	presets := &Presets{byName: make(map[string]Preset)}
+
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, params, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !ok {
+			return nil, fmt.Errorf("preset %q: expected name=params", item)
+		}
+		if !presetNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("preset %q: invalid name", name)
+		}
+		if _, exists := presets.byName[name]; exists {
+			return nil, fmt.Errorf("preset %q: defined twice", name)
+		}
+
+		preset, err := parsePreset(name, params, overlays)
+		if err != nil {
+			return nil, fmt.Errorf("preset %q: %w", name, err)
+		}
+		presets.byName[name] = preset
+		presets.list = append(presets.list, preset)
+	}
+
+	sort.Slice(presets.list, func(i, j int) bool { return presets.list[i].Name < presets.list[j].Name })
+	return presets, nil
+}
+
+// parsePreset разбирает и проверяет параметры одного пресета
+func parsePreset(name, params string, overlays *Overlays) (Preset, error) {
+	preset := Preset{Name: name, Quality: DefaultQuality}
+
+	for _, param := range strings.Split(params, ",") {
+		param = strings.TrimSpace(param)
+		if param == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(param, ":")
+		if !ok {
+			return Preset{}, fmt.Errorf("expected key:value, got %q", param)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		var err error
+		switch key {
+		case "w":
+			preset.Width, err = parseDimension(value)
+		case "h":
+			preset.Height, err = parseDimension(value)
+		case "q":
+			preset.Quality, err = strconv.Atoi(value)
+			if err == nil && (preset.Quality < 1 || preset.Quality > 100) {
+				err = fmt.Errorf("quality must be 1-100")
+			}
+		case "fit":
+			preset.Fit, err = ParseFit(value)
+		case "fm":
+			preset.Format, err = ParseFormat(value)
+		case "poster":
+			preset.Poster, err = strconv.ParseBool(value)
+		case "wm":
+			preset.Watermark = value
+			if overlays == nil || !overlays.HasWatermark(value) {
+				err = fmt.Errorf("unknown watermark %q", value)
+			}
+		case "text":
+			preset.Text = value
+			if overlays == nil || !overlays.HasText(value) {
+				err = fmt.Errorf("unknown text overlay %q", value)
+			}
+		default:
+			err = fmt.Errorf("unknown parameter %q", key)
+		}
+		if err != nil {
+			return Preset{}, err
+		}
+	}
+
+	if preset.Fit != FitFill && (preset.Width == 0 || preset.Height == 0) {
+		return Preset{}, fmt.Errorf("fit:%s requires both w and h", preset.Fit)
+	}
+	return preset, nil
+}
+
+// parseDimension разбирает ширину или высоту пресета
+func parseDimension(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > maxPresetDimension {
+		return 0, fmt.Errorf("dimension must be 1-%d, got %q", maxPresetDimension, value)
+	}
+	return n, nil
+}
+
+// Get возвращает пресет по имени
+func (p *Presets) Get(name string) (Preset, bool) {
+	preset, ok := p.byName[name]
+	return preset, ok
+}
+
+// List возвращает все пресеты, отсортированные по имени
+func (p *Presets) List() []Preset {
+	return append([]Preset(nil), p.list...)
+}
+
+// Match ищет пресет с теми же параметрами обработки и возвращает его имя.
+// Шаблоны ссылаются на такой вариант через /img/{пресет}/..., поэтому ссылки
+// сайта работают и в режиме "только пресеты"
+func (p *Presets) Match(opts ProcessOptions) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	for _, preset := range p.list {
+		if preset.Options() == opts {
+			return preset.Name, true
+		}
+	}
+	return "", false
+}
+
+// MatchSize ищет пресет с теми же параметрами без учета формата. Формат такого
+// пресета меняется параметром fm в ссылке (см. PresetURL): так <picture> отдает
+// один пресет в AVIF, WebP и формате по умолчанию
+func (p *Presets) MatchSize(opts ProcessOptions) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	for _, preset := range p.list {
+		candidate := preset.Options()
+		candidate.Format = opts.Format
+		if candidate == opts {
+			return preset.Name, true
+		}
+	}
+	return "", false
+}
+
+// Allows сообщает, совпадают ли параметры запроса с одним из пресетов.
+// Используется в режиме "только пресеты", чтобы /optimized-image не порождал
+// произвольное число вариантов. Формат может отличаться, как и в /img/: число
+// форматов конечно, и <picture> запрашивает каждый размер в нескольких
+func (p *Presets) Allows(opts ProcessOptions) bool {
+	_, ok := p.MatchSize(opts)
+	return ok
+}
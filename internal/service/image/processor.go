@@ -2,46 +2,258 @@ package image
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"image"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/disintegration/imaging"
+	_ "golang.org/x/image/webp" // декодирование исходников в WebP
 )
 
+// ErrBusy возвращается, если свободный обработчик не нашелся за время ожидания в очереди
+var ErrBusy = errors.New("image processor is busy")
+
+// DefaultQuality качество варианта, если оно не задано в ссылке или пресете
+const DefaultQuality = 80
+
+// ProcessOptions параметры обработки одного варианта изображения
+type ProcessOptions struct {
+	Width   int
+	Height  int
+	Quality int
+	Format  Format
+	Fit     Fit  // учитывается, только если заданы и ширина, и высота
+	Poster  bool // только первый кадр анимированного исходника
+	// Watermark и Text имена водяного знака и надписи из Overlays; задаются только пресетами
+	Watermark string
+	Text      string
+}
+
+// Fit режим вписывания изображения в заданные ширину и высоту
+type Fit string
+
+const (
+	// FitFill растягивает изображение до размеров без сохранения пропорций
+	FitFill Fit = ""
+	// FitCover заполняет размеры целиком, обрезая лишнее по краям от центра
+	FitCover Fit = "cover"
+	// FitContain вписывает изображение в размеры с сохранением пропорций
+	FitContain Fit = "contain"
+)
+
+// ParseFit разбирает значение параметра fit. Пустая строка и "fill" означают FitFill
+func ParseFit(value string) (Fit, error) {
+	switch strings.ToLower(value) {
+	case "", "fill":
+		return FitFill, nil
+	case "cover":
+		return FitCover, nil
+	case "contain":
+		return FitContain, nil
+	default:
+		return "", fmt.Errorf("unknown fit mode: %s", value)
+	}
+}
+
+// ProcessorOptions настройки сервиса обработки изображений
+type ProcessorOptions struct {
+	// Media файловая система с исходниками: os.Root.FS() для каталога на диске
+	// (не выпускает за пределы каталога даже по симлинкам) или embed.FS
+	Media             fs.FS
+	AllowedExtensions []string // разрешенные расширения исходников; пусто - DefaultAllowedExtensions
+	MaxMegapixels     float64  // предельный размер исходника; 0 - без ограничения
+	MaxFrames         int      // предельное число кадров анимации; 0 - без ограничения
+	// MaxAnimationMegapixels предельная суммарная площадь всех кадров анимации; 0 - без ограничения
+	MaxAnimationMegapixels float64
+	Cache                  Cache         // кэш обработанных вариантов; nil - без кэширования
+	MaxConcurrent          int           // сколько изображений декодируется одновременно
+	QueueTimeout           time.Duration // сколько запрос ждет свободного обработчика
+	ProcessTimeout         time.Duration // предельное время обработки одного варианта; 0 - без ограничения
+	// MemoryBudget сколько байт (по оценке) могут занимать все идущие обработки;
+	// вариант, которому нужно больше всего бюджета, отклоняется. 0 - без ограничения
+	MemoryBudget int64
+	Remote       RemoteOptions // удаленные исходники по http(s); без разрешенных хостов выключены
+	Overlays     *Overlays     // водяные знаки и надписи для пресетов; nil - без наложений
+	// BaseContext контекст приложения. Обработка идет отдельно от контекста запроса,
+	// чтобы ее результат достался всем ожидающим, поэтому при остановке сервера
+	// ее прерывает отмена этого контекста. nil - обработка не прерывается
+	BaseContext context.Context
+}
+
 // ProcessorService сервис для обработки изображений
-type ProcessorService struct{}
+type ProcessorService struct {
+	files      *FileLoader
+	remote     *HTTPLoader // nil, если удаленные исходники выключены
+	allowedExt map[string]bool
+	maxPixels  int64
+	// maxFrames и maxAnimationPixels ограничивают анимированные исходники
+	maxFrames          int
+	maxAnimationPixels int64
+	overlays           *Overlays
+
+	cache Cache
+	// flights объединяет одновременные запросы одного и того же варианта
+	flights flightGroup
+	// workers ограничивает число одновременных декодирований: каждое держит
+	// в памяти полный растр исходника, и без лимита всплеск трафика съест всю память
+	workers        chan struct{}
+	queueTimeout   time.Duration
+	processTimeout time.Duration
+	memory         *memoryBudget
+}
 
 // NewProcessorService создает новый экземпляр сервиса
-func NewProcessorService() *ProcessorService {
-	return &ProcessorService{}
+func NewProcessorService(opts ProcessorOptions) *ProcessorService {
+	if opts.Cache == nil {
+		opts.Cache = noCache{}
+	}
+	if opts.MaxConcurrent <= 0 {
+		opts.MaxConcurrent = runtime.NumCPU()
+	}
+	if opts.QueueTimeout <= 0 {
+		opts.QueueTimeout = 5 * time.Second
+	}
+	if len(opts.AllowedExtensions) == 0 {
+		opts.AllowedExtensions = DefaultAllowedExtensions
+	}
+
+	allowedExt := make(map[string]bool, len(opts.AllowedExtensions))
+	for _, ext := range opts.AllowedExtensions {
+		allowedExt[strings.ToLower(ext)] = true
+	}
+
+	var remote *HTTPLoader
+	if len(opts.Remote.AllowedHosts) > 0 {
+		remote = NewHTTPLoader(opts.Remote, opts.Cache)
+		remote.flights.base = opts.BaseContext
+	}
+
+	return &ProcessorService{
+		files:              NewFileLoader(opts.Media),
+		remote:             remote,
+		allowedExt:         allowedExt,
+		maxPixels:          int64(opts.MaxMegapixels * 1_000_000),
+		maxFrames:          opts.MaxFrames,
+		maxAnimationPixels: int64(opts.MaxAnimationMegapixels * 1_000_000),
+		overlays:           opts.Overlays,
+		cache:              opts.Cache,
+		workers:            make(chan struct{}, opts.MaxConcurrent),
+		queueTimeout:       opts.QueueTimeout,
+		processTimeout:     opts.ProcessTimeout,
+		memory:             newMemoryBudget(opts.MemoryBudget),
+		flights:            flightGroup{base: opts.BaseContext},
+	}
 }
 
-// ProcessImage обрабатывает изображение: изменяет размер и конвертирует в оптимизированный формат
-func (ps *ProcessorService) ProcessImage(ctx context.Context, filePath string, width, height, quality int) ([]byte, error) {
+// ProcessImage обрабатывает изображение по публичному пути (/static/..., file://
+// или разрешенный http(s)://): изменяет
+// размер и конвертирует в заданный формат. Готовые варианты берутся из кэша,
+// одновременные запросы одного варианта обрабатываются один раз
+func (ps *ProcessorService) ProcessImage(ctx context.Context, path string, opts ProcessOptions) ([]byte, error) {
+	ctx = WithOriginMemo(ctx)
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
 	}
 
-	// Проверяем, существует ли файл
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+	src, err := ps.resolve(path)
+	if err != nil {
 		return nil, err
 	}
 
-	// Открываем исходное изображение
-	src, err := ps.openImage(filePath)
+	// Проверяем, существует ли исходник; его версия входит в ключ кэша
+	meta, err := src.stat(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	opts, err = ps.resolveFormat(ctx, src, meta, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	key := ps.variantKey(src, meta, opts)
+	if data, found := ps.cache.Get(key); found {
+		return data, nil
+	}
+
+	return ps.flights.Do(ctx, key, func(ctx context.Context) ([]byte, error) {
+		release, err := ps.acquireWorker(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		// Пока ждали обработчика, вариант мог подготовить другой запрос
+		if data, found := ps.cache.Get(key); found {
+			return data, nil
+		}
+
+		data, err := ps.processWithDeadline(ctx, src, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		ps.cache.Set(key, data)
+		return data, nil
+	})
+}
+
+// processWithDeadline ограничивает обработку временем processTimeout. Срабатывание
+// дедлайна отличается от отмены запроса: это ErrProcessingTimeout, а не ошибка контекста
+func (ps *ProcessorService) processWithDeadline(ctx context.Context, src source, opts ProcessOptions) ([]byte, error) {
+	if ps.processTimeout <= 0 {
+		return ps.processImage(ctx, src, opts)
+	}
+
+	deadlineCtx, cancel := context.WithTimeout(ctx, ps.processTimeout)
+	defer cancel()
+
+	data, err := ps.processImage(deadlineCtx, src, opts)
+	if err != nil && ctx.Err() == nil && errors.Is(deadlineCtx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("%w after %s", ErrProcessingTimeout, ps.processTimeout)
+	}
+	return data, err
+}
+
+// processImage выполняет саму обработку: декодирование, ресайз и кодирование.
+// Между шагами проверяется контекст: если клиент ушел или истек дедлайн,
+// следующий тяжелый шаг не начинается
+func (ps *ProcessorService) processImage(ctx context.Context, src source, opts ProcessOptions) ([]byte, error) {
+	// Анимированный GIF в формат с поддержкой анимации обрабатывается покадрово
+	if data, ok, err := ps.processAnimation(ctx, src, opts); ok || err != nil {
+		return data, err
+	}
+
+	// Открываем исходное изображение, заняв под него память из бюджета
+	img, release, err := ps.openImage(ctx, src, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// Изменяем размер изображения, если указаны параметры
-	dst := ps.resizeImage(src, width, height)
+	dst := ps.resizeImage(img, opts.Width, opts.Height, opts.Fit)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Накладываем водяной знак и надпись
+	dst = ps.overlays.Apply(dst, opts)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// Конвертируем в оптимизированный формат и возвращаем байты
-	result, err := ps.encodeOptimizedImage(dst, quality)
+	result, err := ps.encodeOptimizedImage(ctx, dst, opts.Format, opts.Quality)
 	if err != nil {
 		return nil, err
 	}
@@ -49,24 +261,31 @@ func (ps *ProcessorService) ProcessImage(ctx context.Context, filePath string, w
 	return result, nil
 }
 
-// openImage открывает изображение независимо от его формата
-func (ps *ProcessorService) openImage(filePath string) (image.Image, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		_ = file.Close()
-	}()
+// acquireWorker занимает слот обработчика. Если слот не освободился за
+// queueTimeout, возвращается ErrBusy: лучше быстро ответить 503, чем копить очередь
+func (ps *ProcessorService) acquireWorker(ctx context.Context) (func(), error) {
+	timer := time.NewTimer(ps.queueTimeout)
+	defer timer.Stop()
 
-	// Определяем тип изображения по содержимому
-	img, _, err := image.Decode(file)
-	return img, err
+	select {
+	case ps.workers <- struct{}{}:
+		return func() { <-ps.workers }, nil
+	case <-timer.C:
+		return nil, ErrBusy
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
 // resizeImage изменяет размер изображения
-func (ps *ProcessorService) resizeImage(src image.Image, width, height int) image.Image {
+func (ps *ProcessorService) resizeImage(src image.Image, width, height int, fit Fit) image.Image {
 	if width > 0 && height > 0 {
+		switch fit {
+		case FitCover:
+			return imaging.Fill(src, width, height, imaging.Center, imaging.Lanczos)
+		case FitContain:
+			return imaging.Fit(src, width, height, imaging.Lanczos)
+		}
 		return imaging.Resize(src, width, height, imaging.Lanczos)
 	} else if width > 0 {
 		return imaging.Resize(src, width, 0, imaging.Lanczos)
@@ -78,15 +297,17 @@ func (ps *ProcessorService) resizeImage(src image.Image, width, height int) imag
 	}
 }
 
-// encodeOptimizedImage кодирует изображение в оптимизированный формат
-func (ps *ProcessorService) encodeOptimizedImage(img image.Image, quality int) ([]byte, error) {
+// encodeOptimizedImage кодирует изображение в запрошенный формат; отмена ctx прерывает запись
+func (ps *ProcessorService) encodeOptimizedImage(ctx context.Context, img image.Image, format Format, quality int) ([]byte, error) {
+	encode, err := format.encoder()
+	if err != nil {
+		return nil, err
+	}
+
 	var buf []byte
 	writer := &sliceWriter{buf: &buf}
 
-	// Используем JPEG с высоким качеством как временный формат
-	// В будущем можно добавить поддержку WebP, AVIF и других форматов
-	err := imaging.Encode(writer, img, imaging.JPEG, imaging.JPEGQuality(quality))
-	if err != nil {
+	if err := encode(contextWriter{ctx: ctx, w: writer}, img, quality); err != nil {
 		return nil, err
 	}
 
@@ -103,9 +324,138 @@ func (sw *sliceWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
-// ValidateImagePath проверяет, что путь к изображению безопасен
-func (ps *ProcessorService) ValidateImagePath(path string) bool {
-	// Проверяем, что путь не содержит опасных последовательностей
-	normalized := filepath.Clean(path)
-	return normalized == path && !filepath.IsAbs(path) && !strings.Contains(normalized, "..")
+// SourceInfo сведения об исходном изображении для шаблонов
+type SourceInfo struct {
+	Width   int
+	Height  int
+	Version string // меняется при замене файла, используется для версионирования ссылок
+	// Animated анимированный GIF: в формате по умолчанию вариант остается GIF,
+	// а WebP и AVIF сохранили бы только первый кадр
+	Animated bool
+}
+
+// SourceInfo возвращает размеры и версию исходного изображения по публичному пути.
+// Читается только заголовок файла, поэтому вызов дешевый и подходит для рендеринга шаблонов
+func (ps *ProcessorService) SourceInfo(path string) (*SourceInfo, error) {
+	ctx := WithOriginMemo(context.Background())
+
+	src, err := ps.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := src.stat(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := src.loader.Open(ctx, src.name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	cfg, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return nil, err
+	}
+
+	animated, err := ps.isAnimated(ctx, src, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SourceInfo{Width: cfg.Width, Height: cfg.Height, Version: meta.Version, Animated: animated}, nil
+}
+
+// Variant сведения о варианте изображения для HTTP-кэширования
+type Variant struct {
+	ETag         string    // сильный ETag: исходник + параметры обработки
+	LastModified time.Time // время изменения исходника (для удаленных - Last-Modified origin)
+	Version      string    // версия исходника (см. SourceInfo.Version)
+	Format       Format    // формат ответа; для FormatDefault выбран по исходнику
+}
+
+// Variant описывает вариант без его обработки, чтобы на условные запросы
+// можно было ответить 304, не декодируя изображение
+func (ps *ProcessorService) Variant(ctx context.Context, path string, opts ProcessOptions) (*Variant, error) {
+	ctx = WithOriginMemo(ctx)
+	src, err := ps.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := src.stat(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err = ps.resolveFormat(ctx, src, meta, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Variant{
+		ETag:         `"` + ps.variantKey(src, meta, opts) + `"`,
+		LastModified: meta.ModTime.UTC().Truncate(time.Second),
+		Version:      meta.Version,
+		Format:       opts.Format,
+	}, nil
+}
+
+// NegotiateFormat выбирает формат для fm=auto по заголовку Accept. Анимированный
+// исходник остается в формате по умолчанию: WebP и AVIF сохранили бы только первый кадр
+func (ps *ProcessorService) NegotiateFormat(ctx context.Context, path string, opts ProcessOptions, accept string) (Format, error) {
+	ctx = WithOriginMemo(ctx)
+	format := AcceptedFormat(accept)
+	if format == FormatDefault || opts.Poster {
+		return format, nil
+	}
+
+	src, err := ps.resolve(path)
+	if err != nil {
+		return "", err
+	}
+	meta, err := src.stat(ctx)
+	if err != nil {
+		return "", err
+	}
+	animated, err := ps.isAnimated(ctx, src, meta)
+	if err != nil {
+		return "", err
+	}
+	if animated {
+		return FormatDefault, nil
+	}
+	return format, nil
+}
+
+// resolveFormat выбирает формат вместо FormatDefault: анимированный GIF
+// остается GIF, остальное (и кадр-постер) кодируется в JPEG. FormatAuto сюда
+// доходит без запроса (прогрев) и выбирается так же
+func (ps *ProcessorService) resolveFormat(ctx context.Context, src source, meta *SourceMeta, opts ProcessOptions) (ProcessOptions, error) {
+	if opts.Format != FormatDefault && opts.Format != FormatAuto {
+		return opts, nil
+	}
+
+	opts.Format = FormatJPEG
+	if opts.Poster {
+		return opts, nil
+	}
+	animated, err := ps.isAnimated(ctx, src, meta)
+	if err != nil {
+		return opts, err
+	}
+	if animated {
+		opts.Format = FormatGIF
+	}
+	return opts, nil
+}
+
+// variantKey ключ варианта для кэша и ETag. Отпечаток наложений добавляется к
+// версии исходника: замена картинки водяного знака дает новые ключи без сброса кэша
+func (ps *ProcessorService) variantKey(src source, meta *SourceMeta, opts ProcessOptions) string {
+	return GenerateCacheKey(src.name, meta.Version+ps.overlays.Fingerprint(opts), opts)
 }
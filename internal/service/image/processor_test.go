@@ -0,0 +1,124 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// countingFS считает открытия файлов. Stat отвечает без Open, чтобы в счетчик
+// попадало только чтение исходника для декодирования
+type countingFS struct {
+	fstest.MapFS
+	opens atomic.Int64
+}
+
+func (f *countingFS) Open(name string) (fs.File, error) {
+	f.opens.Add(1)
+	return f.MapFS.Open(name)
+}
+
+func (f *countingFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(f.MapFS, name)
+}
+
+// testPNG кодирует однотонную картинку заданного размера
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// newTestProcessor создает сервис над файлом images/a.png с кэшем в памяти
+func newTestProcessor(t *testing.T) (*ProcessorService, *countingFS) {
+	t.Helper()
+	media := &countingFS{MapFS: fstest.MapFS{
+		"images/a.png": {Data: testPNG(t, 64, 48), ModTime: time.Unix(1700000000, 0)},
+	}}
+	cache := NewImageCache(time.Hour, 1<<20)
+	t.Cleanup(func() {
+		_ = cache.Close()
+	})
+	return NewProcessorService(ProcessorOptions{Media: media, Cache: cache, MaxConcurrent: 2}), media
+}
+
+func TestProcessImageServesRepeatFromCache(t *testing.T) {
+	ps, media := newTestProcessor(t)
+	opts := ProcessOptions{Width: 32, Quality: 80, Format: FormatJPEG}
+
+	first, err := ps.ProcessImage(context.Background(), "/static/images/a.png", opts)
+	if err != nil {
+		t.Fatalf("first ProcessImage: %v", err)
+	}
+	if _, format, err := image.DecodeConfig(bytes.NewReader(first)); err != nil || format != "jpeg" {
+		t.Fatalf("first result format = %q, %v; want jpeg", format, err)
+	}
+	opened := media.opens.Load()
+	if opened == 0 {
+		t.Fatal("source was never opened on the first request")
+	}
+
+	second, err := ps.ProcessImage(context.Background(), "/static/images/a.png", opts)
+	if err != nil {
+		t.Fatalf("second ProcessImage: %v", err)
+	}
+	if got := media.opens.Load(); got != opened {
+		t.Fatalf("source opened %d more times on repeat request, want it served from cache", got-opened)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatal("repeat request returned different bytes")
+	}
+
+	// Другой вариант того же исходника в кэше не найден и декодируется заново
+	if _, err := ps.ProcessImage(context.Background(), "/static/images/a.png", ProcessOptions{Width: 16, Quality: 80, Format: FormatJPEG}); err != nil {
+		t.Fatalf("other variant ProcessImage: %v", err)
+	}
+	if got := media.opens.Load(); got == opened {
+		t.Fatal("other variant was served without decoding the source")
+	}
+}
+
+func TestProcessImageCoalescesConcurrentRequests(t *testing.T) {
+	ps, media := newTestProcessor(t)
+	opts := ProcessOptions{Width: 32, Quality: 80, Format: FormatPNG}
+
+	// Эталон: сколько открытий нужно одной обработке
+	reference, _ := newTestProcessor(t)
+	if _, err := reference.ProcessImage(context.Background(), "/static/images/a.png", opts); err != nil {
+		t.Fatalf("reference ProcessImage: %v", err)
+	}
+
+	const requests = 8
+	var wg sync.WaitGroup
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := ps.ProcessImage(context.Background(), "/static/images/a.png", opts); err != nil {
+				t.Errorf("ProcessImage: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got, want := media.opens.Load(), reference.files.fsys.(*countingFS).opens.Load(); got != want {
+		t.Fatalf("%d concurrent requests opened the source %d times, want %d as for one request", requests, got, want)
+	}
+}
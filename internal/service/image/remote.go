@@ -0,0 +1,378 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrHostNotAllowed возвращается для удаленных исходников с хостов вне списка разрешенных
+	ErrHostNotAllowed = errors.New("image host is not allowed")
+	// ErrOriginFailed возвращается, если удаленный исходник не удалось скачать
+	ErrOriginFailed = errors.New("failed to fetch image from origin")
+)
+
+// maxRedirects сколько перенаправлений origin проходит HTTPLoader
+const maxRedirects = 5
+
+// originHeaderSize размер служебного заголовка записи origin в кэше:
+// время скачивания и Last-Modified, по 8 байт
+const originHeaderSize = 16
+
+// originMetaSize размер записи метаданных origin в кэше: заголовок, размер
+// тела (8 байт) и версия (8 байт хэша содержимого)
+const originMetaSize = originHeaderSize + 16
+
+// RemoteOptions настройки загрузки удаленных исходников
+type RemoteOptions struct {
+	// AllowedHosts хосты, с которых разрешено скачивать исходники: "cdn.example.com",
+	// "cdn.example.com:8080" (только этот порт) или "*.example.com" для всех поддоменов. Пусто - удаленные исходники выключены
+	AllowedHosts []string
+	MaxBytes     int64         // предельный размер ответа origin
+	Timeout      time.Duration // таймаут одного запроса к origin
+	TTL          time.Duration // как долго скачанный исходник считается свежим
+}
+
+// HTTPLoader скачивает исходники по http(s) с разрешенных хостов. Ответы origin
+// хранятся в том же кэше, что и обработанные варианты, поэтому повторные запросы
+// не ходят в сеть, пока не истек TTL. Метаданные кэшируются отдельно от тела:
+// тело больше сегмента кэша в памяти не сохраняется, но Stat для готового
+// варианта все равно обходится без скачивания
+type HTTPLoader struct {
+	client   *http.Client
+	hosts    []string
+	maxBytes int64
+	ttl      time.Duration
+	cache    Cache
+	flights  flightGroup
+}
+
+// originEntry скачанный исходник
+type originEntry struct {
+	body         []byte
+	fetchedAt    time.Time
+	lastModified time.Time
+}
+
+// NewHTTPLoader создает загрузчик удаленных исходников
+func NewHTTPLoader(opts RemoteOptions, cache Cache) *HTTPLoader {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 20 << 20
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = time.Hour
+	}
+	if cache == nil {
+		cache = noCache{}
+	}
+
+	hosts := make([]string, 0, len(opts.AllowedHosts))
+	for _, host := range opts.AllowedHosts {
+		hosts = append(hosts, strings.ToLower(strings.TrimSpace(host)))
+	}
+
+	l := &HTTPLoader{
+		hosts:    hosts,
+		maxBytes: opts.MaxBytes,
+		ttl:      opts.TTL,
+		cache:    cache,
+	}
+	l.client = &http.Client{
+		Timeout: opts.Timeout,
+		// Перенаправление не должно уводить на хост вне списка разрешенных
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if !l.allowed(req.URL) {
+				return fmt.Errorf("%w: %s", ErrHostNotAllowed, req.URL.Host)
+			}
+			return nil
+		},
+	}
+	return l
+}
+
+// allowed проверяет схему и хост ссылки по списку разрешенных
+func (l *HTTPLoader) allowed(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+
+	hostPort := strings.ToLower(u.Host)
+	hostname := strings.ToLower(u.Hostname())
+	for _, pattern := range l.hosts {
+		switch {
+		case pattern == hostPort:
+			return true
+		case strings.HasPrefix(pattern, "*."):
+			if strings.HasSuffix(hostname, pattern[1:]) {
+				return true
+			}
+		case pattern == hostname:
+			return true
+		}
+	}
+	return false
+}
+
+// Stat возвращает сведения об удаленном исходнике. Версия считается по
+// содержимому: origin может не отдавать Last-Modified или отдавать его неверно
+func (l *HTTPLoader) Stat(ctx context.Context, name string) (*SourceMeta, error) {
+	if data, found := l.cache.Get(originMetaCacheKey(name)); found {
+		if meta, fetchedAt, ok := decodeOriginMeta(data); ok && time.Since(fetchedAt) < l.ttl {
+			return meta, nil
+		}
+	}
+
+	entry, err := l.fetch(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return entry.meta(), nil
+}
+
+// Open возвращает содержимое удаленного исходника
+func (l *HTTPLoader) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	entry, err := l.fetch(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return readSeekNopCloser{bytes.NewReader(entry.body)}, nil
+}
+
+// fetch берет исходник из памяти запроса (см. WithOriginMemo), из кэша или
+// скачивает его; одновременные запросы одной ссылки скачивают ее один раз
+func (l *HTTPLoader) fetch(ctx context.Context, rawURL string) (*originEntry, error) {
+	memo := originMemoFrom(ctx)
+	if entry, found := memo.get(rawURL); found && time.Since(entry.fetchedAt) < l.ttl {
+		return entry, nil
+	}
+
+	key := originCacheKey(rawURL)
+	if data, found := l.cache.Get(key); found {
+		if entry, ok := decodeOriginEntry(data); ok && time.Since(entry.fetchedAt) < l.ttl {
+			memo.set(rawURL, entry)
+			return entry, nil
+		}
+	}
+
+	data, err := l.flights.Do(ctx, key, func(ctx context.Context) ([]byte, error) {
+		entry, err := l.download(ctx, rawURL)
+		if err != nil {
+			return nil, err
+		}
+		data := encodeOriginEntry(entry)
+		l.cache.Set(key, data)
+		l.cache.Set(originMetaCacheKey(rawURL), encodeOriginMeta(entry))
+		return data, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entry, _ := decodeOriginEntry(data)
+	memo.set(rawURL, entry)
+	return entry, nil
+}
+
+// meta сведения о скачанном исходнике
+func (e *originEntry) meta() *SourceMeta {
+	sum := sha256.Sum256(e.body)
+	return &SourceMeta{
+		ModTime: e.modTime(),
+		Size:    int64(len(e.body)),
+		Version: hex.EncodeToString(sum[:8]),
+	}
+}
+
+// modTime время изменения: Last-Modified origin, а без него - время скачивания
+func (e *originEntry) modTime() time.Time {
+	if e.lastModified.IsZero() {
+		return e.fetchedAt
+	}
+	return e.lastModified
+}
+
+// download скачивает исходник с учетом лимита размера
+func (l *HTTPLoader) download(ctx context.Context, rawURL string) (*originEntry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPath, err)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrHostNotAllowed) {
+			return nil, ErrHostNotAllowed
+		}
+		if errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			log.Printf("Origin %s timed out", rawURL)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrOriginFailed, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, rawURL)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%w: %s returned %d", ErrOriginFailed, rawURL, resp.StatusCode)
+	}
+
+	// Content-Length проверяем заранее, но доверяем только фактически прочитанному
+	if resp.ContentLength > l.maxBytes {
+		return nil, fmt.Errorf("%w: %s is %d bytes", ErrImageTooLarge, rawURL, resp.ContentLength)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, l.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOriginFailed, err)
+	}
+	if int64(len(body)) > l.maxBytes {
+		return nil, fmt.Errorf("%w: %s exceeds %d bytes", ErrImageTooLarge, rawURL, l.maxBytes)
+	}
+
+	entry := &originEntry{body: body, fetchedAt: time.Now()}
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		entry.lastModified = lastModified
+	}
+	return entry, nil
+}
+
+// originCacheKey ключ кэша для скачанного исходника
+func originCacheKey(rawURL string) string {
+	return GenerateCacheKey("origin:"+rawURL, "", ProcessOptions{})
+}
+
+// originMetaCacheKey ключ кэша для метаданных скачанного исходника
+func originMetaCacheKey(rawURL string) string {
+	return GenerateCacheKey("origin-meta:"+rawURL, "", ProcessOptions{})
+}
+
+// encodeOriginMeta упаковывает метаданные исходника для кэша: заголовок как у
+// тела (время скачивания и Last-Modified), размер и версия
+func encodeOriginMeta(entry *originEntry) []byte {
+	meta := entry.meta()
+	version, _ := hex.DecodeString(meta.Version)
+	data := encodeOriginEntry(&originEntry{fetchedAt: entry.fetchedAt, lastModified: entry.lastModified})
+	data = binary.BigEndian.AppendUint64(data, uint64(meta.Size))
+	return append(data, version...)
+}
+
+// decodeOriginMeta распаковывает метаданные исходника и время скачивания
+func decodeOriginMeta(data []byte) (*SourceMeta, time.Time, bool) {
+	if len(data) != originMetaSize {
+		return nil, time.Time{}, false
+	}
+	header, _ := decodeOriginEntry(data[:originHeaderSize])
+	meta := &SourceMeta{
+		ModTime: header.modTime(),
+		Size:    int64(binary.BigEndian.Uint64(data[originHeaderSize : originHeaderSize+8])),
+		Version: hex.EncodeToString(data[originHeaderSize+8:]),
+	}
+	return meta, header.fetchedAt, true
+}
+
+// originMemo исходники, скачанные за время одного запроса
+type originMemo struct {
+	mutex   sync.Mutex
+	entries map[string]*originEntry
+}
+
+// originMemoKey ключ originMemo в контексте
+type originMemoKey struct{}
+
+// WithOriginMemo запоминает удаленные исходники на время контекста ctx: Stat,
+// чтение заголовка и Open одного запроса скачивают исходник один раз, даже
+// если тело не помещается в кэш. Сервис вызывает его сам, а обработчик - чтобы
+// Variant и ProcessImage одного HTTP-запроса разделили скачанное
+func WithOriginMemo(ctx context.Context) context.Context {
+	if originMemoFrom(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, originMemoKey{}, &originMemo{entries: make(map[string]*originEntry)})
+}
+
+// originMemoFrom достает память запроса из контекста; nil, если ее нет
+func originMemoFrom(ctx context.Context) *originMemo {
+	memo, _ := ctx.Value(originMemoKey{}).(*originMemo)
+	return memo
+}
+
+// get возвращает запомненный исходник
+func (m *originMemo) get(rawURL string) (*originEntry, bool) {
+	if m == nil {
+		return nil, false
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	entry, found := m.entries[rawURL]
+	return entry, found
+}
+
+// set запоминает исходник
+func (m *originMemo) set(rawURL string, entry *originEntry) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.entries[rawURL] = entry
+}
+
+// encodeOriginEntry упаковывает исходник для кэша: заголовок и тело
+func encodeOriginEntry(entry *originEntry) []byte {
+	data := make([]byte, originHeaderSize+len(entry.body))
+	binary.BigEndian.PutUint64(data[0:8], uint64(entry.fetchedAt.UnixNano()))
+	var lastModified int64
+	if !entry.lastModified.IsZero() {
+		lastModified = entry.lastModified.Unix()
+	}
+	binary.BigEndian.PutUint64(data[8:16], uint64(lastModified))
+	copy(data[originHeaderSize:], entry.body)
+	return data
+}
+
+// decodeOriginEntry распаковывает исходник из кэша
+func decodeOriginEntry(data []byte) (*originEntry, bool) {
+	if len(data) < originHeaderSize {
+		return nil, false
+	}
+	entry := &originEntry{
+		body:      data[originHeaderSize:],
+		fetchedAt: time.Unix(0, int64(binary.BigEndian.Uint64(data[0:8]))),
+	}
+	if lastModified := int64(binary.BigEndian.Uint64(data[8:16])); lastModified != 0 {
+		entry.lastModified = time.Unix(lastModified, 0)
+	}
+	return entry, true
+}
+
+// readSeekNopCloser io.ReadSeeker с пустым Close
+type readSeekNopCloser struct {
+	io.ReadSeeker
+}
+
+func (readSeekNopCloser) Close() error { return nil }
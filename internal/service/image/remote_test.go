@@ -0,0 +1,372 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// originServer поднимает origin и считает обращения к нему
+func originServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+// newTestLoader создает загрузчик, которому разрешен только хост srv
+func newTestLoader(t *testing.T, srv *httptest.Server, opts RemoteOptions) *HTTPLoader {
+	t.Helper()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("parse server URL: %v", err)
+	}
+	opts.AllowedHosts = []string{u.Host}
+	cache := NewImageCache(time.Hour, 1<<20)
+	t.Cleanup(func() {
+		_ = cache.Close()
+	})
+	return NewHTTPLoader(opts, cache)
+}
+
+// readAll читает исходник через Open
+func readAll(t *testing.T, l *HTTPLoader, rawURL string) (string, error) {
+	t.Helper()
+	file, err := l.Open(context.Background(), rawURL)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return string(data), nil
+}
+
+func TestHTTPLoaderAllowedHosts(t *testing.T) {
+	l := NewHTTPLoader(RemoteOptions{AllowedHosts: []string{"cdn.example.com", "img.example.com:8080", "*.static.example.com"}}, nil)
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://cdn.example.com/a.png", true},
+		{"http://CDN.example.com:9000/a.png", true},
+		{"https://img.example.com:8080/a.png", true},
+		{"https://img.example.com/a.png", false},
+		{"https://img.example.com:9090/a.png", false},
+		{"https://eu.static.example.com/a.png", true},
+		{"https://static.example.com/a.png", false},
+		{"https://evilstatic.example.com/a.png", false},
+		{"https://cdn.example.com.evil.org/a.png", false},
+		{"ftp://cdn.example.com/a.png", false},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatalf("parse %s: %v", tt.url, err)
+		}
+		if got := l.allowed(u); got != tt.want {
+			t.Errorf("allowed(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestResolveRemoteRejectsDisallowedHost(t *testing.T) {
+	srv, hits := originServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "image")
+	})
+
+	ps := NewProcessorService(ProcessorOptions{Remote: RemoteOptions{AllowedHosts: []string{"cdn.example.com"}}})
+	if _, err := ps.resolve(srv.URL + "/a.png"); !errors.Is(err, ErrHostNotAllowed) {
+		t.Fatalf("resolve(disallowed host) = %v, want ErrHostNotAllowed", err)
+	}
+
+	// Без разрешенных хостов удаленные исходники выключены целиком
+	ps = NewProcessorService(ProcessorOptions{})
+	if _, err := ps.resolve(srv.URL + "/a.png"); !errors.Is(err, ErrHostNotAllowed) {
+		t.Fatalf("resolve(remote disabled) = %v, want ErrHostNotAllowed", err)
+	}
+	if hits.Load() != 0 {
+		t.Fatalf("origin was requested %d times, want none", hits.Load())
+	}
+}
+
+func TestHTTPLoaderFollowsRedirectOnAllowedHost(t *testing.T) {
+	srv, _ := originServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old.png" {
+			http.Redirect(w, r, "/new.png", http.StatusFound)
+			return
+		}
+		_, _ = io.WriteString(w, "new image")
+	})
+	l := newTestLoader(t, srv, RemoteOptions{})
+
+	body, err := readAll(t, l, srv.URL+"/old.png")
+	if err != nil || body != "new image" {
+		t.Fatalf("Open(redirect) = %q, %v; want \"new image\"", body, err)
+	}
+}
+
+func TestHTTPLoaderRejectsRedirectToDisallowedHost(t *testing.T) {
+	other, otherHits := originServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "secret")
+	})
+	srv, _ := originServer(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, other.URL+"/a.png", http.StatusFound)
+	})
+	l := newTestLoader(t, srv, RemoteOptions{})
+
+	if _, err := readAll(t, l, srv.URL+"/a.png"); !errors.Is(err, ErrHostNotAllowed) {
+		t.Fatalf("Open(redirect to other host) = %v, want ErrHostNotAllowed", err)
+	}
+	if otherHits.Load() != 0 {
+		t.Fatalf("disallowed host was requested %d times, want none", otherHits.Load())
+	}
+}
+
+func TestHTTPLoaderMaxBytes(t *testing.T) {
+	srv, _ := originServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body := strings.Repeat("x", 100)
+		if r.URL.Path == "/chunked.png" {
+			// Без Content-Length лимит проверяется по прочитанному
+			w.Header().Set("Transfer-Encoding", "chunked")
+			_, _ = io.WriteString(w, body[:50])
+			w.(http.Flusher).Flush()
+			_, _ = io.WriteString(w, body[50:])
+			return
+		}
+		_, _ = io.WriteString(w, body)
+	})
+	l := newTestLoader(t, srv, RemoteOptions{MaxBytes: 99})
+
+	for _, path := range []string{"/a.png", "/chunked.png"} {
+		if _, err := readAll(t, l, srv.URL+path); !errors.Is(err, ErrImageTooLarge) {
+			t.Errorf("Open(%s) = %v, want ErrImageTooLarge", path, err)
+		}
+	}
+
+	l = newTestLoader(t, srv, RemoteOptions{MaxBytes: 100})
+	if body, err := readAll(t, l, srv.URL+"/a.png"); err != nil || len(body) != 100 {
+		t.Fatalf("Open(at limit) = %d bytes, %v; want 100 bytes", len(body), err)
+	}
+}
+
+func TestHTTPLoaderTimeout(t *testing.T) {
+	srv, _ := originServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	})
+	l := newTestLoader(t, srv, RemoteOptions{Timeout: 50 * time.Millisecond})
+
+	start := time.Now()
+	if _, err := readAll(t, l, srv.URL+"/slow.png"); !errors.Is(err, ErrOriginFailed) {
+		t.Fatalf("Open(slow origin) = %v, want ErrOriginFailed", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Open(slow origin) took %s, want it cut by the timeout", elapsed)
+	}
+}
+
+func TestHTTPLoaderStatusErrors(t *testing.T) {
+	srv, _ := originServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing.png":
+			http.NotFound(w, r)
+		default:
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}
+	})
+	l := newTestLoader(t, srv, RemoteOptions{})
+
+	if _, err := readAll(t, l, srv.URL+"/missing.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open(404) = %v, want ErrNotFound", err)
+	}
+	if _, err := readAll(t, l, srv.URL+"/broken.png"); !errors.Is(err, ErrOriginFailed) {
+		t.Errorf("Open(500) = %v, want ErrOriginFailed", err)
+	}
+}
+
+func TestHTTPLoaderCachesOrigin(t *testing.T) {
+	lastModified := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	release := make(chan struct{})
+	srv, hits := originServer(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+		_, _ = io.WriteString(w, "image")
+	})
+	l := newTestLoader(t, srv, RemoteOptions{TTL: time.Hour})
+	rawURL := srv.URL + "/a.png"
+
+	// Одновременные запросы одной ссылки скачивают ее один раз
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if body, err := readAll(t, l, rawURL); err != nil || body != "image" {
+				t.Errorf("Open = %q, %v; want \"image\"", body, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	meta, err := l.Stat(context.Background(), rawURL)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if !meta.ModTime.Equal(lastModified) || meta.Size != 5 || meta.Version == "" {
+		t.Fatalf("Stat = %+v, want Last-Modified %s, 5 bytes and a version", meta, lastModified)
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("origin was requested %d times, want 1", hits.Load())
+	}
+}
+
+func TestHTTPLoaderRefetchesAfterTTL(t *testing.T) {
+	srv, hits := originServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "image")
+	})
+	l := newTestLoader(t, srv, RemoteOptions{TTL: 20 * time.Millisecond})
+	rawURL := srv.URL + "/a.png"
+
+	if _, err := readAll(t, l, rawURL); err != nil {
+		t.Fatalf("first Open: %v", err)
+	}
+	if _, err := readAll(t, l, rawURL); err != nil {
+		t.Fatalf("second Open: %v", err)
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("origin was requested %d times within TTL, want 1", hits.Load())
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, err := readAll(t, l, rawURL); err != nil {
+		t.Fatalf("Open after TTL: %v", err)
+	}
+	if hits.Load() != 2 {
+		t.Fatalf("origin was requested %d times after TTL, want 2", hits.Load())
+	}
+}
+
+// noisyPNG PNG из случайных пикселей: почти не сжимается, поэтому больше сегмента маленького кэша
+func noisyPNG(t *testing.T, size int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(rng.Uint32())
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestHTTPLoaderSharesLargeBodyWithinRequest(t *testing.T) {
+	body := strings.Repeat("x", 200)
+	srv, hits := originServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, body)
+	})
+	u, _ := url.Parse(srv.URL)
+	// Сегмент кэша 64 байта: тело в кэш не попадает, метаданные попадают
+	cache := NewImageCache(time.Hour, 64*cacheShards)
+	t.Cleanup(func() {
+		_ = cache.Close()
+	})
+	l := NewHTTPLoader(RemoteOptions{AllowedHosts: []string{u.Host}, TTL: time.Hour}, cache)
+	rawURL := srv.URL + "/a.png"
+
+	ctx := WithOriginMemo(context.Background())
+	meta, err := l.Stat(ctx, rawURL)
+	if err != nil || meta.Size != 200 {
+		t.Fatalf("Stat = %+v, %v; want 200 bytes", meta, err)
+	}
+	for range 2 {
+		file, err := l.Open(ctx, rawURL)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		_ = file.Close()
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("origin was requested %d times within one request, want 1", hits.Load())
+	}
+
+	// Следующему запросу метаданные достаются из кэша, тело скачивается заново
+	again, err := l.Stat(context.Background(), rawURL)
+	if err != nil || *again != *meta {
+		t.Fatalf("cached Stat = %+v, %v; want %+v", again, err, meta)
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("Stat with cached metadata requested origin, %d hits", hits.Load())
+	}
+	if _, err := readAll(t, l, rawURL); err != nil || hits.Load() != 2 {
+		t.Fatalf("Open in a new request = %v, %d hits; want a second download", err, hits.Load())
+	}
+}
+
+func TestProcessImageDownloadsLargeRemoteSourceOnce(t *testing.T) {
+	source := noisyPNG(t, 64)
+	srv, hits := originServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(source)
+	})
+	u, _ := url.Parse(srv.URL)
+	// Сегмент кэша 4 КБ: исходник (~16 КБ) в него не помещается, маленький вариант помещается
+	cache := NewImageCache(time.Hour, 4<<10*cacheShards)
+	t.Cleanup(func() {
+		_ = cache.Close()
+	})
+	ps := NewProcessorService(ProcessorOptions{
+		Cache:  cache,
+		Remote: RemoteOptions{AllowedHosts: []string{u.Host}},
+	})
+	rawURL := srv.URL + "/a.png"
+	opts := ProcessOptions{Width: 8, Quality: 80}
+
+	// Как в обработчике: Variant и ProcessImage одного HTTP-запроса
+	ctx := WithOriginMemo(context.Background())
+	variant, err := ps.Variant(ctx, rawURL, opts)
+	if err != nil {
+		t.Fatalf("Variant: %v", err)
+	}
+	opts.Format = variant.Format
+	if _, err := ps.ProcessImage(ctx, rawURL, opts); err != nil {
+		t.Fatalf("ProcessImage: %v", err)
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("origin was requested %d times for one image request, want 1", hits.Load())
+	}
+
+	// Готовый вариант отдается по кэшированным метаданным без скачивания
+	if _, err := ps.ProcessImage(context.Background(), rawURL, opts); err != nil {
+		t.Fatalf("repeat ProcessImage: %v", err)
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("cached variant requested origin, %d hits", hits.Load())
+	}
+}
@@ -0,0 +1,235 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"log"
+	"net/url"
+	pathpkg "path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MediaURLPrefix публичный префикс исходных изображений: /static/images/a.png
+// соответствует файлу images/a.png в каталоге медиа
+const MediaURLPrefix = "/static/"
+
+var (
+	// ErrInvalidPath возвращается для неподдерживаемых схем и ненормализованных путей (.., //, \)
+	ErrInvalidPath = errors.New("invalid image path")
+	// ErrNotFound возвращается, если исходник не найден
+	ErrNotFound = errors.New("image not found")
+	// ErrExtensionNotAllowed возвращается для файлов с неразрешенным расширением
+	ErrExtensionNotAllowed = errors.New("image extension is not allowed")
+	// ErrImageTooLarge возвращается, если исходник больше допустимого числа пикселей
+	ErrImageTooLarge = errors.New("image is too large")
+)
+
+// DefaultAllowedExtensions расширения исходников, разрешенные по умолчанию
+var DefaultAllowedExtensions = []string{".jpg", ".jpeg", ".png", ".gif", ".webp"}
+
+// SourceMeta сведения об исходнике, из которых строятся ключи кэша и валидаторы HTTP
+type SourceMeta struct {
+	ModTime time.Time
+	Size    int64
+	Version string // меняется вместе с содержимым исходника
+}
+
+// Loader загрузчик исходников одного вида (локальные файлы, HTTP-origin)
+type Loader interface {
+	Stat(ctx context.Context, name string) (*SourceMeta, error)
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+}
+
+// source исходник, найденный по публичному пути
+type source struct {
+	loader Loader
+	name   string // имя внутри загрузчика; оно же входит в ключ кэша
+}
+
+// FileLoader читает исходники из файловой системы: os.Root.FS() для каталога
+// на диске (не выпускает за его пределы даже по симлинкам) или embed.FS
+type FileLoader struct {
+	fsys fs.FS
+}
+
+// NewFileLoader создает загрузчик локальных файлов
+func NewFileLoader(fsys fs.FS) *FileLoader {
+	return &FileLoader{fsys: fsys}
+}
+
+// Stat возвращает сведения о файле
+func (l *FileLoader) Stat(_ context.Context, name string) (*SourceMeta, error) {
+	info, err := fs.Stat(l.fsys, name)
+	if err != nil {
+		return nil, fileError(name, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return &SourceMeta{
+		ModTime: info.ModTime(),
+		Size:    info.Size(),
+		Version: strconv.FormatInt(info.ModTime().UnixNano(), 36) + "-" + strconv.FormatInt(info.Size(), 36),
+	}, nil
+}
+
+// Open открывает файл
+func (l *FileLoader) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	file, err := l.fsys.Open(name)
+	if err != nil {
+		return nil, fileError(name, err)
+	}
+	return file, nil
+}
+
+// fileError приводит ошибку файловой системы к ErrNotFound. Выход за пределы
+// каталога через симлинк или нехватка прав наружу выглядят так же, как
+// отсутствие файла, чтобы не раскрывать устройство файловой системы
+func fileError(name string, err error) error {
+	if !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("Image source %s: %v", name, err)
+	}
+	return fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// resolve проверяет публичный путь и выбирает загрузчик:
+//   - /static/images/a.png и file:///images/a.png - файл images/a.png в каталоге медиа;
+//   - http(s)://host/a.png - удаленный исходник, если хост в списке разрешенных.
+//
+// fs.ValidPath отсекает "..", пустые и ненормализованные элементы, поэтому
+// /static/../data/data.db не превращается в путь за пределами каталога
+func (ps *ProcessorService) resolve(path string) (source, error) {
+	switch {
+	case strings.HasPrefix(path, "http://"), strings.HasPrefix(path, "https://"):
+		return ps.resolveRemote(path)
+	case strings.HasPrefix(path, "file://"):
+		u, err := url.Parse(path)
+		if err != nil || u.Host != "" {
+			return source{}, ErrInvalidPath
+		}
+		return ps.resolveFile(strings.TrimPrefix(u.Path, "/"))
+	case strings.HasPrefix(path, MediaURLPrefix):
+		return ps.resolveFile(strings.TrimPrefix(path, MediaURLPrefix))
+	default:
+		return source{}, ErrInvalidPath
+	}
+}
+
+// resolveFile проверяет имя локального файла
+func (ps *ProcessorService) resolveFile(name string) (source, error) {
+	if name == "" || !fs.ValidPath(name) || strings.Contains(name, `\`) {
+		return source{}, ErrInvalidPath
+	}
+	if err := ps.checkExtension(name); err != nil {
+		return source{}, err
+	}
+	return source{loader: ps.files, name: name}, nil
+}
+
+// resolveRemote проверяет ссылку на удаленный исходник
+func (ps *ProcessorService) resolveRemote(rawURL string) (source, error) {
+	if ps.remote == nil {
+		return source{}, ErrHostNotAllowed
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || u.User != nil {
+		return source{}, ErrInvalidPath
+	}
+	if !ps.remote.allowed(u) {
+		return source{}, ErrHostNotAllowed
+	}
+	if err := ps.checkExtension(u.Path); err != nil {
+		return source{}, err
+	}
+
+	// Фрагмент на сервер не уходит и не должен плодить разные ключи кэша
+	u.Fragment = ""
+	return source{loader: ps.remote, name: u.String()}, nil
+}
+
+// checkExtension проверяет расширение по списку разрешенных
+func (ps *ProcessorService) checkExtension(name string) error {
+	if !ps.allowedExt[strings.ToLower(pathpkg.Ext(name))] {
+		return ErrExtensionNotAllowed
+	}
+	return nil
+}
+
+// stat возвращает сведения об исходнике
+func (src source) stat(ctx context.Context) (*SourceMeta, error) {
+	return src.loader.Stat(ctx, src.name)
+}
+
+// openImage декодирует исходник. Перед полным декодированием читается только
+// заголовок: так маленький файл с огромными заявленными размерами
+// (decompression bomb) отсекается до того, как под растр выделится память.
+// Оценка памяти на вариант opts занимается из бюджета; release возвращает ее
+func (ps *ProcessorService) openImage(ctx context.Context, src source, opts ProcessOptions) (image.Image, func(), error) {
+	file, err := src.loader.Open(ctx, src.name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	cfg, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := ps.checkPixels(cfg.Width, cfg.Height); err != nil {
+		return nil, nil, err
+	}
+
+	// Память занимается до декодирования: по заголовку уже известно, сколько ее понадобится
+	release, err := ps.memory.reserve(ctx, estimateMemory(cfg, opts), ps.queueTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	img, err := decodeFrom(ctx, src, file)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return img, release, nil
+}
+
+// decodeFrom декодирует открытый файл исходника с начала
+func decodeFrom(ctx context.Context, src source, file io.ReadCloser) (image.Image, error) {
+	// Перематываем файл к началу; если загрузчик этого не умеет, открываем заново
+	var reader io.Reader
+	if seeker, ok := file.(io.Seeker); ok {
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		reader = file
+	} else {
+		again, err := src.loader.Open(ctx, src.name)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = again.Close()
+		}()
+		reader = again
+	}
+
+	// Определяем тип изображения по содержимому
+	img, _, err := image.Decode(contextReader{ctx: ctx, r: reader})
+	return img, err
+}
+
+// checkPixels проверяет размеры исходника по лимиту мегапикселей
+func (ps *ProcessorService) checkPixels(width, height int) error {
+	if ps.maxPixels > 0 && int64(width)*int64(height) > ps.maxPixels {
+		return fmt.Errorf("%w: %dx%d", ErrImageTooLarge, width, height)
+	}
+	return nil
+}
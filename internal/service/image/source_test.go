@@ -0,0 +1,136 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestResolveLocalPaths(t *testing.T) {
+	ps := NewProcessorService(ProcessorOptions{Media: fstest.MapFS{}})
+
+	tests := []struct {
+		path    string
+		name    string // имя файла в каталоге медиа, если путь допустим
+		wantErr error
+	}{
+		{path: "/static/images/a.png", name: "images/a.png"},
+		{path: "/static/images/a.PNG", name: "images/a.PNG"},
+		{path: "file:///images/a.png", name: "images/a.png"},
+		// Абсолютный путь в file:// отсчитывается от каталога медиа
+		{path: "file:///etc/a.png", name: "etc/a.png"},
+		// Путь уже декодирован роутером: %2e%2e второй раз не раскрывается
+		{path: "/static/%2e%2e/a.png", name: "%2e%2e/a.png"},
+
+		{path: "/static/../data/data.png", wantErr: ErrInvalidPath},
+		{path: "/static/images/../../a.png", wantErr: ErrInvalidPath},
+		{path: "/static/./images/a.png", wantErr: ErrInvalidPath},
+		{path: "/static//etc/a.png", wantErr: ErrInvalidPath},
+		{path: `/static/images\..\a.png`, wantErr: ErrInvalidPath},
+		{path: "/static/", wantErr: ErrInvalidPath},
+		{path: "file:///%2e%2e/a.png", wantErr: ErrInvalidPath},
+		{path: "file://host/a.png", wantErr: ErrInvalidPath},
+		{path: "/etc/a.png", wantErr: ErrInvalidPath},
+		{path: "images/a.png", wantErr: ErrInvalidPath},
+		{path: "ftp://example.com/a.png", wantErr: ErrInvalidPath},
+		{path: "https://example.com/a.png", wantErr: ErrHostNotAllowed},
+
+		{path: "/static/data/data.db", wantErr: ErrExtensionNotAllowed},
+		{path: "/static/images/a.svg", wantErr: ErrExtensionNotAllowed},
+		{path: "/static/images/a", wantErr: ErrExtensionNotAllowed},
+		{path: "file:///etc/passwd", wantErr: ErrExtensionNotAllowed},
+	}
+	for _, tt := range tests {
+		src, err := ps.resolve(tt.path)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("resolve(%q) = %q, %v; want %v", tt.path, src.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || src.name != tt.name {
+			t.Errorf("resolve(%q) = %q, %v; want %q", tt.path, src.name, err, tt.name)
+		}
+	}
+}
+
+func TestResolveAllowedExtensions(t *testing.T) {
+	ps := NewProcessorService(ProcessorOptions{
+		Media:             fstest.MapFS{},
+		AllowedExtensions: []string{".PNG", ".avif"},
+	})
+
+	tests := map[string]error{
+		"/static/a.png":  nil,
+		"/static/a.Png":  nil,
+		"/static/a.avif": nil,
+		"/static/a.jpg":  ErrExtensionNotAllowed,
+		"/static/a.gif":  ErrExtensionNotAllowed,
+	}
+	for path, want := range tests {
+		if _, err := ps.resolve(path); !errors.Is(err, want) {
+			t.Errorf("resolve(%q) = %v, want %v", path, err, want)
+		}
+	}
+}
+
+func TestFileLoaderStaysInsideMediaRoot(t *testing.T) {
+	base := t.TempDir()
+	mediaDir := filepath.Join(base, "media")
+	if err := os.MkdirAll(filepath.Join(mediaDir, "images"), 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	png := testPNG(t, 8, 4)
+	if err := os.WriteFile(filepath.Join(mediaDir, "images", "a.png"), png, 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(base, "secret.png"), png, 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	links := map[string]string{
+		"inside.png":   "a.png",                                 // внутри каталога медиа
+		"outside.png":  filepath.Join("..", "..", "secret.png"), // относительный выход наружу
+		"absolute.png": filepath.Join(base, "secret.png"),       // абсолютный путь наружу
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(mediaDir, "images", name)); err != nil {
+			t.Skipf("symlinks are not supported: %v", err)
+		}
+	}
+
+	root, err := os.OpenRoot(mediaDir)
+	if err != nil {
+		t.Fatalf("OpenRoot: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = root.Close()
+	})
+	ps := NewProcessorService(ProcessorOptions{Media: root.FS()})
+
+	tests := map[string]error{
+		"/static/images/a.png":        nil,
+		"/static/images/inside.png":   nil,
+		"/static/images/outside.png":  ErrNotFound,
+		"/static/images/absolute.png": ErrNotFound,
+		"/static/images/missing.png":  ErrNotFound,
+		"/static/images":              ErrExtensionNotAllowed,
+	}
+	for path, want := range tests {
+		info, err := ps.SourceInfo(path)
+		if !errors.Is(err, want) {
+			t.Errorf("SourceInfo(%q) = %+v, %v; want %v", path, info, err, want)
+			continue
+		}
+		if want == nil && (info.Width != 8 || info.Height != 4) {
+			t.Errorf("SourceInfo(%q) = %dx%d, want 8x4", path, info.Width, info.Height)
+		}
+	}
+
+	// Выход наружу закрыт и для обработки, а не только для SourceInfo
+	if _, err := ps.ProcessImage(context.Background(), "/static/images/outside.png", ProcessOptions{Width: 4}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("ProcessImage through escaping symlink = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,45 @@
+package image
+
+import "errors"
+
+// TieredCache цепочка кэшей: быстрые уровни впереди, медленные и большие позади.
+// Промах на первом уровне проверяется на следующих, найденное поднимается наверх
+type TieredCache struct {
+	tiers []Cache
+}
+
+// NewTieredCache создает многоуровневый кэш
+func NewTieredCache(tiers ...Cache) *TieredCache {
+	return &TieredCache{tiers: tiers}
+}
+
+// Get ищет элемент по уровням и копирует найденное на более быстрые уровни
+func (c *TieredCache) Get(key string) ([]byte, bool) {
+	for i, tier := range c.tiers {
+		data, found := tier.Get(key)
+		if !found {
+			continue
+		}
+		for _, upper := range c.tiers[:i] {
+			upper.Set(key, data)
+		}
+		return data, true
+	}
+	return nil, false
+}
+
+// Set записывает элемент на все уровни
+func (c *TieredCache) Set(key string, data []byte) {
+	for _, tier := range c.tiers {
+		tier.Set(key, data)
+	}
+}
+
+// Close закрывает все уровни, у которых есть фоновые задачи
+func (c *TieredCache) Close() error {
+	var errs []error
+	for _, tier := range c.tiers {
+		errs = append(errs, CloseCache(tier))
+	}
+	return errors.Join(errs...)
+}
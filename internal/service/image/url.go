@@ -0,0 +1,63 @@
+package image
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// OptimizedImageRoute маршрут, который отдает обработанные изображения
+const OptimizedImageRoute = "/optimized-image"
+
+// PresetRoute префикс маршрутов изображений в именованных пресетах
+const PresetRoute = "/img"
+
+// BuildURL формирует ссылку на обработанный вариант изображения.
+// Нулевые параметры не попадают в ссылку, чтобы один и тот же вариант
+// всегда имел одинаковый URL и лучше кэшировался браузером. Если передана
+// версия исходника, ответ по такой ссылке кэшируется навсегда (immutable)
+func BuildURL(path string, opts ProcessOptions, version string) string {
+	query := url.Values{}
+	query.Set("path", path)
+	if opts.Width > 0 {
+		query.Set("w", strconv.Itoa(opts.Width))
+	}
+	if opts.Height > 0 {
+		query.Set("h", strconv.Itoa(opts.Height))
+	}
+	if opts.Quality > 0 {
+		query.Set("q", strconv.Itoa(opts.Quality))
+	}
+	if opts.Format != FormatDefault {
+		query.Set("fm", string(opts.Format))
+	}
+	if opts.Fit != FitFill {
+		query.Set("fit", string(opts.Fit))
+	}
+	if opts.Poster {
+		query.Set("poster", "1")
+	}
+	if version != "" {
+		query.Set("v", version)
+	}
+	return OptimizedImageRoute + "?" + query.Encode()
+}
+
+// PresetURL формирует ссылку на изображение в именованном пресете:
+// PresetURL("thumb", "/static/images/a.png", "", "") = "/img/thumb/images/a.png".
+// format, если задан, заменяет формат пресета (?fm=webp для <source> в <picture>).
+// Версия исходника, как и в BuildURL, делает ответ кэшируемым навсегда
+func PresetURL(preset, path string, format Format, version string) string {
+	link := PresetRoute + "/" + preset + "/" + strings.TrimPrefix(path, MediaURLPrefix)
+	query := url.Values{}
+	if format != FormatDefault {
+		query.Set("fm", string(format))
+	}
+	if version != "" {
+		query.Set("v", version)
+	}
+	if len(query) > 0 {
+		link += "?" + query.Encode()
+	}
+	return link
+}
@@ -0,0 +1,251 @@
+package image
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// warmupLogInterval как часто прогрев пишет прогресс в лог
+const warmupLogInterval = 5 * time.Second
+
+// WarmupOptions настройки прогрева кэша
+type WarmupOptions struct {
+	// Manifest файл со списком публичных путей (/static/images/a.png), по одному
+	// на строку; пустые строки и строки с # пропускаются. Пусто - обходится Dir
+	Manifest string
+	// Dir каталог внутри каталога медиа, который обходится без манифеста, например "images"
+	Dir    string
+	Widths []int // ширины вариантов; 0 - исходный размер
+	// Formats форматы вариантов; неподдерживаемые пропускаются. Пусто - как в
+	// шаблонах: формат по умолчанию и PictureFormats
+	Formats []Format
+	// Presets именованные пресеты: для каждого исходника готовится их вариант,
+	// а для пресетов без формата и с fm:auto - варианты во всех форматах из Formats
+	Presets     []Preset
+	Quality     int // качество, как в ссылках шаблонов (по умолчанию DefaultQuality)
+	Concurrency int // сколько вариантов готовится одновременно
+}
+
+// WarmupStats итог прогрева
+type WarmupStats struct {
+	Total    int
+	Done     int
+	Skipped  int // ширина больше исходника: такой вариант шаблоны не запрашивают
+	Failed   int
+	Duration time.Duration
+}
+
+// warmupJob один вариант для прогрева
+type warmupJob struct {
+	path string
+	opts ProcessOptions
+}
+
+// Warmup заранее готовит варианты изображений и складывает их в кэш, чтобы
+// первые посетители не ждали ресайза. Работает через ProcessImage, поэтому
+// занимает обычные слоты обработчиков: Concurrency стоит держать меньше
+// MaxConcurrent, чтобы прогрев не отнимал их все у живых запросов
+func (ps *ProcessorService) Warmup(ctx context.Context, opts WarmupOptions) (WarmupStats, error) {
+	start := time.Now()
+
+	if opts.Quality <= 0 {
+		opts.Quality = DefaultQuality
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if len(opts.Widths) == 0 {
+		opts.Widths = []int{0}
+	}
+
+	var formats []Format
+	for _, format := range opts.Formats {
+		if format.IsSupported() {
+			formats = append(formats, format)
+		} else {
+			log.Printf("Image warm-up: format %s is not supported, skipping", format)
+		}
+	}
+	if len(formats) == 0 {
+		formats = append([]Format{FormatDefault}, PictureFormats...)
+	}
+
+	paths, err := ps.warmupPaths(opts)
+	if err != nil {
+		return WarmupStats{}, err
+	}
+
+	jobs := ps.warmupJobs(paths, opts.Widths, formats, opts.Quality, opts.Presets)
+	stats := WarmupStats{Total: len(jobs.list), Skipped: jobs.skipped}
+
+	var done, failed atomic.Int64
+	queue := make(chan warmupJob)
+	var wg sync.WaitGroup
+	for range opts.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				if _, err := ps.ProcessImage(ctx, job.path, job.opts); err != nil {
+					if !errors.Is(err, context.Canceled) {
+						log.Printf("Image warm-up: %s %dpx %s: %v", job.path, job.opts.Width, job.opts.Format, err)
+						failed.Add(1)
+					}
+					continue
+				}
+				done.Add(1)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(warmupLogInterval)
+	defer ticker.Stop()
+
+feed:
+	for _, job := range jobs.list {
+		for {
+			select {
+			case queue <- job:
+				continue feed
+			case <-ticker.C:
+				log.Printf("Image warm-up: %d/%d variants ready, %d failed", done.Load(), stats.Total, failed.Load())
+			case <-ctx.Done():
+				break feed
+			}
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	stats.Done = int(done.Load())
+	stats.Failed = int(failed.Load())
+	stats.Duration = time.Since(start)
+	return stats, ctx.Err()
+}
+
+// warmupPaths собирает публичные пути исходников из манифеста или каталога
+func (ps *ProcessorService) warmupPaths(opts WarmupOptions) ([]string, error) {
+	if opts.Manifest != "" {
+		return readManifest(opts.Manifest)
+	}
+
+	dir := strings.Trim(opts.Dir, "/")
+	if dir == "" {
+		dir = "."
+	}
+
+	var paths []string
+	err := fs.WalkDir(ps.files.fsys, dir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || ps.checkExtension(name) != nil {
+			return nil
+		}
+		paths = append(paths, MediaURLPrefix+name)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+	return paths, nil
+}
+
+// readManifest читает список путей из файла
+func readManifest(name string) ([]string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open warm-up manifest: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var paths []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		paths = append(paths, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read warm-up manifest: %w", err)
+	}
+	return paths, nil
+}
+
+// warmupJobList варианты для прогрева и число пропущенных
+type warmupJobList struct {
+	list    []warmupJob
+	skipped int
+}
+
+// warmupJobs строит варианты: пути × ширины × форматы и пути × пресеты. Ширины
+// больше исходника пропускаются так же, как их отбрасывает компонент ResponsiveImage,
+// а для анимированных исходников - форматы кроме FormatDefault: шаблоны отдают их
+// одним <img>. Пресет, совпавший с вариантом по ширине, второй раз не добавляется
+func (ps *ProcessorService) warmupJobs(paths []string, widths []int, formats []Format, quality int, presets []Preset) warmupJobList {
+	var jobs warmupJobList
+	for _, path := range paths {
+		info, err := ps.SourceInfo(path)
+		if err != nil {
+			log.Printf("Image warm-up: %s: %v", path, err)
+			continue
+		}
+
+		seen := make(map[ProcessOptions]bool)
+		add := func(opts ProcessOptions) {
+			if !seen[opts] {
+				seen[opts] = true
+				jobs.list = append(jobs.list, warmupJob{path: path, opts: opts})
+			}
+		}
+
+		sourceFormats := formats
+		if info.Animated {
+			sourceFormats = []Format{FormatDefault}
+		}
+
+		for _, width := range widths {
+			if width > info.Width {
+				jobs.skipped += len(sourceFormats)
+				continue
+			}
+			for _, format := range sourceFormats {
+				add(ProcessOptions{Width: width, Quality: quality, Format: format})
+			}
+		}
+		for _, preset := range presets {
+			// Пресет с fm:auto без запроса - тот же вариант, что и в формате по умолчанию
+			if preset.Format != FormatAuto {
+				add(preset.Options())
+			}
+			if preset.Format != FormatDefault && preset.Format != FormatAuto {
+				continue
+			}
+			for _, format := range sourceFormats {
+				opts := preset.Options()
+				opts.Format = format
+				add(opts)
+			}
+		}
+	}
+	return jobs
+}
+
+// String описывает итог прогрева для лога
+func (s WarmupStats) String() string {
+	return fmt.Sprintf("%d/%d variants ready, %d failed, %d skipped in %s",
+		s.Done, s.Total, s.Failed, s.Skipped, s.Duration.Round(time.Millisecond))
+}
@@ -0,0 +1,58 @@
+package image
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWarmupIncludesPresets(t *testing.T) {
+	ps, _ := newTestProcessor(t)
+
+	presets, err := ParsePresets("small=w:32,q:80; thumb=w:16,h:16,fit:cover,fm:auto,q:75", nil)
+	if err != nil {
+		t.Fatalf("ParsePresets: %v", err)
+	}
+
+	// Исходник 64px: ширина 100 пропускается, пресет small совпадает с вариантом 32px.
+	// Форматы по умолчанию как в шаблонах: исходный, AVIF и WebP; пресет с fm:auto - в каждом из них
+	stats, err := ps.Warmup(context.Background(), WarmupOptions{
+		Dir:     "images",
+		Widths:  []int{32, 100},
+		Quality: 80,
+		Presets: presets.List(),
+	})
+	if err != nil {
+		t.Fatalf("Warmup: %v", err)
+	}
+	if stats.Total != 6 || stats.Done != 6 || stats.Skipped != 3 || stats.Failed != 0 {
+		t.Fatalf("Warmup stats = %+v, want 6 total, 6 done, 3 skipped", stats)
+	}
+
+	thumb, _ := presets.Get("thumb")
+	for _, format := range []Format{FormatDefault, FormatWebP} {
+		opts := thumb.Options()
+		opts.Format = format
+		key := ps.variantKeyForTest(t, "/static/images/a.png", opts)
+		if _, found := ps.cache.Get(key); !found {
+			t.Fatalf("preset variant in format %q is not in cache after warm-up", format)
+		}
+	}
+}
+
+// variantKeyForTest ключ кэша варианта, как его считает ProcessImage
+func (ps *ProcessorService) variantKeyForTest(t *testing.T, path string, opts ProcessOptions) string {
+	t.Helper()
+	src, err := ps.resolve(path)
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	meta, err := src.stat(context.Background())
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	opts, err = ps.resolveFormat(context.Background(), src, meta, opts)
+	if err != nil {
+		t.Fatalf("resolveFormat: %v", err)
+	}
+	return ps.variantKey(src, meta, opts)
+}
@@ -0,0 +1,199 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3Options настройки S3-совместимого хранилища (AWS S3, MinIO, Ceph RGW и т.п.)
+type S3Options struct {
+	Endpoint  string // адрес API: https://s3.eu-central-1.amazonaws.com или http://localhost:9000
+	Region    string // регион для подписи запросов; MinIO принимает us-east-1
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PublicURL string // публичный адрес бакета (CDN); пусто - Endpoint/Bucket
+	Timeout   time.Duration
+}
+
+// S3Storage хранит файлы в S3-совместимом бакете. Запросы подписываются
+// AWS Signature V4 вручную: для PUT и DELETE одного объекта SDK не нужен.
+// Используется path-style адресация (endpoint/bucket/key), ее понимают все реализации
+type S3Storage struct {
+	client   *http.Client
+	endpoint *url.URL
+	opts     S3Options
+}
+
+// NewS3Storage создает хранилище в S3-совместимом бакете
+func NewS3Storage(opts S3Options) (*S3Storage, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(opts.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", opts.Endpoint)
+	}
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is required")
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+
+	return &S3Storage{
+		client:   &http.Client{Timeout: opts.Timeout},
+		endpoint: endpoint,
+		opts:     opts,
+	}, nil
+}
+
+// Put загружает объект в бакет
+func (s *S3Storage) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	// Имя файла - хэш содержимого, поэтому объект можно кэшировать навсегда
+	req.Header.Set("Cache-Control", "public, max-age=31536000, immutable")
+
+	return s.do(req)
+}
+
+// Delete удаляет объект из бакета; S3 отвечает 204 и для отсутствующих объектов
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	return s.do(req)
+}
+
+// URL возвращает публичную ссылку на объект
+func (s *S3Storage) URL(key string) string {
+	if s.opts.PublicURL != "" {
+		return strings.TrimSuffix(s.opts.PublicURL, "/") + "/" + key
+	}
+	return s.endpoint.String() + s.objectPath(key)
+}
+
+// newRequest создает подписанный запрос к объекту
+func (s *S3Storage) newRequest(ctx context.Context, method, key string, body []byte) (*http.Request, error) {
+	target := *s.endpoint
+	target.Path = s.objectPath(key)
+	target.RawPath = s.objectURI(key)
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build S3 request: %w", err)
+	}
+	req.ContentLength = int64(len(body))
+
+	s.sign(req, body, time.Now().UTC())
+	return req, nil
+}
+
+// do выполняет запрос и переводит ответ S3 с ошибкой в error
+func (s *S3Storage) do(req *http.Request) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("S3 request failed: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("S3 %s %s returned %d: %s", req.Method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// objectPath путь объекта для path-style адресации
+func (s *S3Storage) objectPath(key string) string {
+	return strings.TrimSuffix(s.endpoint.Path, "/") + "/" + s.opts.Bucket + "/" + key
+}
+
+// objectURI закодированный путь объекта, он же canonical URI для подписи
+func (s *S3Storage) objectURI(key string) string {
+	return awsURIEncode(s.objectPath(key))
+}
+
+// sign подписывает запрос по AWS Signature V4
+func (s *S3Storage) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(), // совпадает с objectURI: RawPath задан в newRequest
+		"",                    // query string
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.opts.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.opts.SecretKey), date)
+	key = hmacSHA256(key, s.opts.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.opts.AccessKey, scope, signedHeaders, signature))
+}
+
+// awsURIEncode кодирует строку по правилам SigV4: без изменений остаются
+// только A-Z, a-z, 0-9, '-', '.', '_', '~' и разделитель пути '/'
+func awsURIEncode(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
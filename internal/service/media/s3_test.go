@@ -0,0 +1,215 @@
+package media
+
+import (
+	"context"
+	"crypto/hmac"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testAccessKey = "minioadmin"
+	testSecretKey = "minio-secret"
+	testBucket    = "media"
+	testRegion    = "us-east-1"
+)
+
+// s3Object объект в fakeS3
+type s3Object struct {
+	data         []byte
+	contentType  string
+	cacheControl string
+}
+
+// fakeS3 заменяет MinIO: path-style PUT и DELETE объектов одного бакета
+// с проверкой подписи AWS Signature V4
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]s3Object
+	secret  string
+}
+
+// newFakeS3 поднимает fakeS3 на httptest.Server
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	t.Helper()
+	fake := &fakeS3{objects: make(map[string]s3Object), secret: testSecretKey}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	return fake, srv
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s3Error(w, http.StatusBadRequest, "IncompleteBody")
+		return
+	}
+	if !f.validSignature(r, body) {
+		s3Error(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+testBucket+"/")
+	if !ok || key == "" {
+		s3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[key] = s3Object{
+			data:         body,
+			contentType:  r.Header.Get("Content-Type"),
+			cacheControl: r.Header.Get("Cache-Control"),
+		}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// validSignature пересчитывает подпись запроса так, как это делает S3
+func (f *fakeS3) validSignature(r *http.Request, body []byte) bool {
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	amzDate := r.Header.Get("X-Amz-Date")
+	if payloadHash != sha256Hex(body) || len(amzDate) != len("20060102T150405Z") {
+		return false
+	}
+
+	scope := amzDate[:8] + "/" + testRegion + "/s3/aws4_request"
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.RawQuery,
+		"host:" + r.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+f.secret), amzDate[:8])
+	key = hmacSHA256(key, testRegion)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	want := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%x",
+		testAccessKey, scope, signedHeaders, hmacSHA256(key, stringToSign))
+
+	return hmac.Equal([]byte(r.Header.Get("Authorization")), []byte(want))
+}
+
+// object возвращает сохраненный объект
+func (f *fakeS3) object(key string) (s3Object, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, ok := f.objects[key]
+	return obj, ok
+}
+
+// s3Error отвечает ошибкой в формате S3
+func s3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<Error><Code>%s</Code></Error>", code)
+}
+
+// newTestS3Storage создает хранилище, направленное на fakeS3
+func newTestS3Storage(t *testing.T, endpoint string, opts S3Options) *S3Storage {
+	t.Helper()
+	opts.Endpoint = endpoint
+	opts.Bucket = testBucket
+	opts.Region = testRegion
+	opts.AccessKey = testAccessKey
+	if opts.SecretKey == "" {
+		opts.SecretKey = testSecretKey
+	}
+	storage, err := NewS3Storage(opts)
+	if err != nil {
+		t.Fatalf("NewS3Storage: %v", err)
+	}
+	return storage
+}
+
+func TestS3StoragePutAndDelete(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	storage := newTestS3Storage(t, srv.URL, S3Options{})
+	ctx := context.Background()
+
+	key := "uploads/ab/photo with space+plus.png"
+	if err := storage.Put(ctx, key, []byte("png data"), "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	obj, ok := fake.object(key)
+	if !ok {
+		t.Fatalf("object %q was not stored", key)
+	}
+	if string(obj.data) != "png data" || obj.contentType != "image/png" {
+		t.Fatalf("stored object = %q %s, want \"png data\" image/png", obj.data, obj.contentType)
+	}
+	if !strings.Contains(obj.cacheControl, "immutable") {
+		t.Fatalf("Cache-Control = %q, want immutable", obj.cacheControl)
+	}
+
+	if err := storage.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := fake.object(key); ok {
+		t.Fatal("object still exists after Delete")
+	}
+	// Удаление отсутствующего объекта S3 ошибкой не считает
+	if err := storage.Delete(ctx, key); err != nil {
+		t.Fatalf("second Delete: %v", err)
+	}
+}
+
+func TestS3StorageRejectedSignature(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	storage := newTestS3Storage(t, srv.URL, S3Options{SecretKey: "wrong"})
+
+	err := storage.Put(context.Background(), "uploads/a.png", []byte("data"), "image/png")
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Fatalf("Put with wrong secret = %v, want 403 SignatureDoesNotMatch", err)
+	}
+	if _, ok := fake.object("uploads/a.png"); ok {
+		t.Fatal("object stored despite rejected signature")
+	}
+}
+
+func TestS3StorageURL(t *testing.T) {
+	storage := newTestS3Storage(t, "http://localhost:9000/", S3Options{})
+	if got, want := storage.URL("uploads/a.png"), "http://localhost:9000/media/uploads/a.png"; got != want {
+		t.Errorf("URL() = %q, want %q", got, want)
+	}
+
+	storage = newTestS3Storage(t, "http://localhost:9000", S3Options{PublicURL: "https://cdn.example.com/"})
+	if got, want := storage.URL("uploads/a.png"), "https://cdn.example.com/uploads/a.png"; got != want {
+		t.Errorf("URL() with PublicURL = %q, want %q", got, want)
+	}
+}
+
+func TestNewS3StorageValidatesOptions(t *testing.T) {
+	tests := []S3Options{
+		{Endpoint: "", Bucket: "media"},
+		{Endpoint: "ftp://localhost:9000", Bucket: "media"},
+		{Endpoint: "http://localhost:9000", Bucket: ""},
+	}
+	for _, opts := range tests {
+		if _, err := NewS3Storage(opts); err == nil {
+			t.Errorf("NewS3Storage(%+v) = nil error, want invalid options", opts)
+		}
+	}
+}
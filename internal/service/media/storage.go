@@ -0,0 +1,98 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Storage хранилище загруженных файлов. Ключ - путь со слешами
+// (uploads/ab/<sha256>.png); по одному ключу всегда лежит одно и то же
+// содержимое, поэтому повторная запись того же файла безопасна
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// LocalStorage хранит файлы в каталоге на диске. Если каталог публикуется
+// как /static/, загруженные изображения сразу доступны сервису обработки
+type LocalStorage struct {
+	dir       string
+	urlPrefix string
+}
+
+// NewLocalStorage создает хранилище в каталоге dir; urlPrefix - публичный
+// префикс этого каталога, например "/static/"
+func NewLocalStorage(dir, urlPrefix string) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage dir: %w", err)
+	}
+	return &LocalStorage{dir: dir, urlPrefix: strings.TrimSuffix(urlPrefix, "/") + "/"}, nil
+}
+
+// Put сохраняет файл. Запись атомарная: файл появляется под своим ключом
+// только целиком, и обработчик изображений не увидит его наполовину записанным
+func (s *LocalStorage) Put(_ context.Context, key string, data []byte, _ string) error {
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return fmt.Errorf("failed to create storage dir: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to chmod file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return fmt.Errorf("failed to store file: %w", err)
+	}
+	return nil
+}
+
+// Delete удаляет файл; отсутствие файла ошибкой не считается
+func (s *LocalStorage) Delete(_ context.Context, key string) error {
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// URL возвращает публичную ссылку на файл
+func (s *LocalStorage) URL(key string) string {
+	return s.urlPrefix + key
+}
+
+// path переводит ключ в путь на диске, не выпуская за пределы каталога
+func (s *LocalStorage) path(key string) (string, error) {
+	if !fs.ValidPath(key) || key != path.Clean(key) || strings.Contains(key, `\`) {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
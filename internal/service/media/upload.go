@@ -0,0 +1,217 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"path"
+
+	"github.com/disintegration/imaging"
+	_ "golang.org/x/image/webp" // декодирование загружаемых WebP
+)
+
+var (
+	// ErrTooLarge возвращается, если файл больше допустимого размера
+	ErrTooLarge = errors.New("file is too large")
+	// ErrUnsupportedType возвращается, если содержимое файла не поддерживаемое изображение
+	ErrUnsupportedType = errors.New("unsupported file type")
+	// ErrInvalidImage возвращается, если изображение не удалось декодировать
+	ErrInvalidImage = errors.New("invalid image")
+	// ErrDimensionsTooLarge возвращается, если размеры изображения превышают лимит
+	ErrDimensionsTooLarge = errors.New("image dimensions are too large")
+)
+
+// sniffLen сколько байт смотрит http.DetectContentType
+const sniffLen = 512
+
+// UploadOptions ограничения и параметры загрузки
+type UploadOptions struct {
+	MaxBytes      int64   // предельный размер файла
+	MaxDimension  int     // предельная ширина и высота в пикселях
+	MaxMegapixels float64 // предельная площадь; 0 - без ограничения
+	JPEGQuality   int     // качество перекодирования JPEG
+	KeyPrefix     string  // каталог внутри хранилища, например "uploads"
+}
+
+// Upload результат загрузки: файл уже лежит в хранилище
+type Upload struct {
+	Key         string
+	URL         string
+	ContentType string
+	Size        int64
+	Width       int
+	Height      int
+}
+
+// Uploader проверяет загружаемые изображения, перекодирует их и сохраняет
+// в хранилище под именем из хэша содержимого
+type Uploader struct {
+	storage Storage
+	opts    UploadOptions
+}
+
+// NewUploader создает сервис загрузки изображений
+func NewUploader(storage Storage, opts UploadOptions) *Uploader {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 10 << 20
+	}
+	if opts.MaxDimension <= 0 {
+		opts.MaxDimension = 8192
+	}
+	if opts.JPEGQuality <= 0 {
+		opts.JPEGQuality = 90
+	}
+	if opts.KeyPrefix == "" {
+		opts.KeyPrefix = "uploads"
+	}
+	return &Uploader{storage: storage, opts: opts}
+}
+
+// MaxBytes предельный размер загружаемого файла
+func (u *Uploader) MaxBytes() int64 {
+	return u.opts.MaxBytes
+}
+
+// Upload читает изображение, проверяет его и сохраняет. Тип определяется по
+// содержимому, а не по имени файла или заголовку клиента. Файл всегда
+// перекодируется: так отбрасываются EXIF (с геометкой), комментарии и любые
+// данные, приклеенные к изображению (polyglot-файлы)
+func (u *Uploader) Upload(ctx context.Context, r io.Reader) (*Upload, error) {
+	data, err := io.ReadAll(io.LimitReader(r, u.opts.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if int64(len(data)) > u.opts.MaxBytes {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrTooLarge, u.opts.MaxBytes)
+	}
+
+	contentType := http.DetectContentType(data[:min(len(data), sniffLen)])
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+
+	// Размеры проверяем по заголовку, до выделения памяти под растр
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if err := u.checkDimensions(cfg.Width, cfg.Height); err != nil {
+		return nil, err
+	}
+
+	encoded, contentType, err := u.reencode(data, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	// Повторная проверка: после поворота по EXIF ширина и высота меняются местами
+	cfg, _, err = image.DecodeConfig(bytes.NewReader(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	sum := sha256.Sum256(encoded)
+	hash := hex.EncodeToString(sum[:])
+	key := path.Join(u.opts.KeyPrefix, hash[:2], hash+extension(contentType))
+
+	if err := u.storage.Put(ctx, key, encoded, contentType); err != nil {
+		return nil, err
+	}
+
+	return &Upload{
+		Key:         key,
+		URL:         u.storage.URL(key),
+		ContentType: contentType,
+		Size:        int64(len(encoded)),
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+	}, nil
+}
+
+// Delete удаляет сохраненный файл, например если запись о нем не попала в базу
+func (u *Uploader) Delete(ctx context.Context, key string) error {
+	return u.storage.Delete(ctx, key)
+}
+
+// checkDimensions проверяет размеры по лимитам
+func (u *Uploader) checkDimensions(width, height int) error {
+	if width > u.opts.MaxDimension || height > u.opts.MaxDimension {
+		return fmt.Errorf("%w: %dx%d, limit is %d px per side", ErrDimensionsTooLarge, width, height, u.opts.MaxDimension)
+	}
+	if u.opts.MaxMegapixels > 0 && float64(width)*float64(height) > u.opts.MaxMegapixels*1_000_000 {
+		return fmt.Errorf("%w: %dx%d, limit is %g MP", ErrDimensionsTooLarge, width, height, u.opts.MaxMegapixels)
+	}
+	return nil
+}
+
+// reencode декодирует и заново кодирует изображение. GIF сохраняет все кадры,
+// WebP перекодируется в PNG без потерь: кодировщика WebP в стандартной библиотеке нет
+func (u *Uploader) reencode(data []byte, contentType string) ([]byte, string, error) {
+	var buf bytes.Buffer
+
+	switch contentType {
+	case "image/gif":
+		anim, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrInvalidImage, err)
+		}
+		// Комментарии и блоки приложений (кроме счетчика повторов) не переносятся
+		clean := &gif.GIF{
+			Image:           anim.Image,
+			Delay:           anim.Delay,
+			LoopCount:       anim.LoopCount,
+			Disposal:        anim.Disposal,
+			Config:          anim.Config,
+			BackgroundIndex: anim.BackgroundIndex,
+		}
+		if err := gif.EncodeAll(&buf, clean); err != nil {
+			return nil, "", fmt.Errorf("failed to encode gif: %w", err)
+		}
+		return buf.Bytes(), contentType, nil
+
+	case "image/jpeg":
+		// EXIF теряется при перекодировании, поэтому ориентацию применяем к пикселям
+		img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrInvalidImage, err)
+		}
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: u.opts.JPEGQuality}); err != nil {
+			return nil, "", fmt.Errorf("failed to encode jpeg: %w", err)
+		}
+		return buf.Bytes(), contentType, nil
+
+	default: // image/png, image/webp
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrInvalidImage, err)
+		}
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		if err := encoder.Encode(&buf, img); err != nil {
+			return nil, "", fmt.Errorf("failed to encode png: %w", err)
+		}
+		return buf.Bytes(), "image/png", nil
+	}
+}
+
+// extension расширение файла для типа содержимого
+func extension(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	default:
+		return ".png"
+	}
+}
@@ -0,0 +1,111 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// encodePNG кодирует однотонную картинку заданного размера
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 200
+	}
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestUploaderStoresReencodedImageInS3(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	uploader := NewUploader(newTestS3Storage(t, srv.URL, S3Options{}), UploadOptions{})
+
+	// Данные, приклеенные после изображения, при перекодировании пропадают
+	data := append(encodePNG(t, 40, 30), []byte("<?php echo 'polyglot'; ?>")...)
+	upload, err := uploader.Upload(context.Background(), bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+
+	if upload.Width != 40 || upload.Height != 30 || upload.ContentType != "image/png" {
+		t.Fatalf("Upload = %dx%d %s, want 40x30 image/png", upload.Width, upload.Height, upload.ContentType)
+	}
+	if !strings.HasPrefix(upload.Key, "uploads/") || !strings.HasSuffix(upload.Key, ".png") {
+		t.Fatalf("Key = %q, want uploads/<hash>.png", upload.Key)
+	}
+	if upload.URL != srv.URL+"/"+testBucket+"/"+upload.Key {
+		t.Fatalf("URL = %q, want object URL in bucket", upload.URL)
+	}
+
+	obj, ok := fake.object(upload.Key)
+	if !ok {
+		t.Fatalf("object %q was not stored", upload.Key)
+	}
+	if bytes.Contains(obj.data, []byte("polyglot")) {
+		t.Fatal("stored object keeps data appended to the image")
+	}
+	if int64(len(obj.data)) != upload.Size || obj.contentType != "image/png" {
+		t.Fatalf("stored object = %d bytes %s, want %d bytes image/png", len(obj.data), obj.contentType, upload.Size)
+	}
+
+	// Одинаковое содержимое ложится под тот же ключ
+	again, err := uploader.Upload(context.Background(), bytes.NewReader(data))
+	if err != nil || again.Key != upload.Key {
+		t.Fatalf("repeat Upload = %v, %v; want same key %q", again, err, upload.Key)
+	}
+
+	if err := uploader.Delete(context.Background(), upload.Key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := fake.object(upload.Key); ok {
+		t.Fatal("object still exists after Delete")
+	}
+}
+
+func TestUploaderRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		opts UploadOptions
+		want error
+	}{
+		{"not an image", []byte("<html><body>hello</body></html>"), UploadOptions{}, ErrUnsupportedType},
+		{"too large", encodePNG(t, 40, 30), UploadOptions{MaxBytes: 10}, ErrTooLarge},
+		{"too wide", encodePNG(t, 40, 30), UploadOptions{MaxDimension: 32}, ErrDimensionsTooLarge},
+		{"too many pixels", encodePNG(t, 40, 30), UploadOptions{MaxMegapixels: 0.001}, ErrDimensionsTooLarge},
+		{"broken image", append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...), UploadOptions{}, ErrInvalidImage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, srv := newFakeS3(t)
+			uploader := NewUploader(newTestS3Storage(t, srv.URL, S3Options{}), tt.opts)
+
+			if _, err := uploader.Upload(context.Background(), bytes.NewReader(tt.data)); !errors.Is(err, tt.want) {
+				t.Fatalf("Upload = %v, want %v", err, tt.want)
+			}
+			if len(fake.objects) != 0 {
+				t.Fatalf("%d objects stored for rejected upload, want none", len(fake.objects))
+			}
+		})
+	}
+}
+
+func TestUploaderStorageError(t *testing.T) {
+	_, srv := newFakeS3(t)
+	uploader := NewUploader(newTestS3Storage(t, srv.URL, S3Options{SecretKey: "wrong"}), UploadOptions{})
+
+	if _, err := uploader.Upload(context.Background(), bytes.NewReader(encodePNG(t, 4, 4))); err == nil {
+		t.Fatal("Upload with failing storage = nil error")
+	}
+}
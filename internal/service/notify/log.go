@@ -0,0 +1,22 @@
+package notify
+
+import (
+	"context"
+	"log"
+
+	"gin-starter/internal/models"
+)
+
+// LogNotifier пишет сообщение в журнал сервера; удобен при разработке
+type LogNotifier struct{}
+
+// NewLogNotifier создает уведомление в журнал
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+// Notify пишет сообщение в журнал
+func (n *LogNotifier) Notify(_ context.Context, message *models.ContactMessage) error {
+	log.Printf("📨 Contact message #%d from %q <%s>: %q", message.ID, message.Name, message.Email, message.Message)
+	return nil
+}
@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gin-starter/internal/models"
+)
+
+// Notifier доставляет уведомление о новом сообщении формы обратной связи
+type Notifier interface {
+	Notify(ctx context.Context, message *models.ContactMessage) error
+}
+
+// Multi отправляет уведомление всем получателям по очереди: сбой одного
+// не мешает остальным, ошибки собираются вместе
+type Multi []Notifier
+
+// Notify отправляет уведомление всем получателям
+func (m Multi) Notify(ctx context.Context, message *models.ContactMessage) error {
+	var errs []error
+	for _, notifier := range m {
+		if err := notifier.Notify(ctx, message); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Options настройки уведомлений для New
+type Options struct {
+	SMTP       SMTPOptions
+	WebhookURL string
+}
+
+// New собирает уведомления по списку видов: "log", "smtp", "webhook".
+// Ошибка в настройках возвращается сразу, а не на первом сообщении
+func New(kinds []string, opts Options) (Notifier, error) {
+	var result Multi
+	for _, kind := range kinds {
+		switch strings.ToLower(strings.TrimSpace(kind)) {
+		case "":
+			continue
+		case "log":
+			result = append(result, NewLogNotifier())
+		case "smtp":
+			notifier, err := NewSMTPNotifier(opts.SMTP)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, notifier)
+		case "webhook":
+			notifier, err := NewWebhookNotifier(opts.WebhookURL)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, notifier)
+		default:
+			return nil, fmt.Errorf("unknown notifier %q", kind)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,171 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gin-starter/internal/models"
+)
+
+// SMTPOptions настройки отправки писем
+type SMTPOptions struct {
+	Host     string
+	Port     int // 465 - TLS сразу; иначе STARTTLS, если сервер его предлагает
+	Username string
+	Password string
+	From     string   // адрес отправителя, можно с именем: "Сайт <noreply@example.com>"
+	To       []string // получатели
+}
+
+// SMTPNotifier отправляет письмо о сообщении. Reply-To - адрес автора,
+// поэтому ответить ему можно прямо из почтового клиента
+type SMTPNotifier struct {
+	opts SMTPOptions
+	from *mail.Address
+	to   []*mail.Address
+}
+
+// NewSMTPNotifier проверяет настройки и создает уведомление по почте
+func NewSMTPNotifier(opts SMTPOptions) (*SMTPNotifier, error) {
+	if opts.Host == "" {
+		return nil, errors.New("smtp: SMTP_HOST is required")
+	}
+	if opts.Port <= 0 || opts.Port > 65535 {
+		return nil, fmt.Errorf("smtp: invalid port %d", opts.Port)
+	}
+
+	from, err := mail.ParseAddress(opts.From)
+	if err != nil {
+		return nil, fmt.Errorf("smtp: invalid from address %q: %w", opts.From, err)
+	}
+	if len(opts.To) == 0 {
+		return nil, errors.New("smtp: at least one recipient is required")
+	}
+	to := make([]*mail.Address, 0, len(opts.To))
+	for _, value := range opts.To {
+		address, err := mail.ParseAddress(value)
+		if err != nil {
+			return nil, fmt.Errorf("smtp: invalid recipient %q: %w", value, err)
+		}
+		to = append(to, address)
+	}
+
+	return &SMTPNotifier{opts: opts, from: from, to: to}, nil
+}
+
+// Notify отправляет письмо; время соединения ограничивает контекст
+func (n *SMTPNotifier) Notify(ctx context.Context, message *models.ContactMessage) error {
+	body, err := n.buildMessage(message)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(n.opts.Host, strconv.Itoa(n.opts.Port))
+	tlsConfig := &tls.Config{ServerName: n.opts.Host}
+	var conn net.Conn
+	if n.opts.Port == 465 {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("smtp: failed to connect: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.opts.Host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("smtp: failed to start session: %w", err)
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	if _, isTLS := conn.(*tls.Conn); !isTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("smtp: STARTTLS failed: %w", err)
+			}
+		}
+	}
+	// PlainAuth сам откажется передавать пароль без TLS на чужой хост
+	if n.opts.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.opts.Username, n.opts.Password, n.opts.Host)); err != nil {
+			return fmt.Errorf("smtp: auth failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(n.from.Address); err != nil {
+		return fmt.Errorf("smtp: MAIL FROM failed: %w", err)
+	}
+	for _, to := range n.to {
+		if err := client.Rcpt(to.Address); err != nil {
+			return fmt.Errorf("smtp: RCPT TO %s failed: %w", to.Address, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp: DATA failed: %w", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("smtp: failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp: message rejected: %w", err)
+	}
+	return client.Quit()
+}
+
+// buildMessage собирает письмо. Имя автора попадает в заголовки только
+// через mail.Address и mime.QEncoding: перевод строки в нем не создаст
+// лишний заголовок
+func (n *SMTPNotifier) buildMessage(message *models.ContactMessage) ([]byte, error) {
+	recipients := make([]string, 0, len(n.to))
+	for _, to := range n.to {
+		recipients = append(recipients, to.String())
+	}
+	replyTo := &mail.Address{Name: message.Name, Address: message.Email}
+
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		buf.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", n.from.String())
+	header("To", strings.Join(recipients, ", "))
+	header("Reply-To", replyTo.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", "Сообщение с сайта от "+message.Name))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<contact-%d-%d@%s>", message.ID, time.Now().UnixNano(), n.opts.Host))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	text := fmt.Sprintf("Новое сообщение #%d с формы обратной связи\n\n"+
+		"Имя: %s\nEmail: %s\nДата: %s\nЯзык: %s\nIP: %s\n\n%s\n",
+		message.ID, message.Name, message.Email,
+		message.CreatedAt.Format("02.01.2006 15:04 MST"), message.Locale, message.IP, message.Message)
+
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(text)); err != nil {
+		return nil, fmt.Errorf("smtp: failed to encode message: %w", err)
+	}
+	if err := qp.Close(); err != nil {
+		return nil, fmt.Errorf("smtp: failed to encode message: %w", err)
+	}
+	return buf.Bytes(), nil
+}
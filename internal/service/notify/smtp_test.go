@@ -0,0 +1,293 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gin-starter/internal/models"
+)
+
+// smtpSession то, что фейковый сервер получил за одно соединение
+type smtpSession struct {
+	auth string // расшифрованный AUTH PLAIN: "\x00user\x00password"
+	from string
+	to   []string
+	data string
+}
+
+// fakeSMTPServer минимальный SMTP-сервер на net.Listen без STARTTLS.
+// rejectRcpt - код ответа на RCPT TO (0 - принять)
+type fakeSMTPServer struct {
+	listener   net.Listener
+	rejectRcpt int
+	sessions   chan smtpSession
+}
+
+// newFakeSMTPServer запускает сервер на 127.0.0.1; PlainAuth разрешает
+// передавать пароль без TLS только на localhost
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	s := &fakeSMTPServer{listener: listener, sessions: make(chan smtpSession, 1)}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	go s.serve()
+	return s
+}
+
+// port порт сервера
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// serve принимает соединения по одному
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.handle(conn)
+	}
+}
+
+// handle ведет одну SMTP-сессию и по QUIT или обрыву отдает ее в sessions
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) {
+		_, _ = io.WriteString(conn, line+"\r\n")
+	}
+	var session smtpSession
+	defer func() {
+		s.sessions <- session
+	}()
+
+	reply("220 localhost ESMTP test")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(command) {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			_, encoded, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(encoded)
+			session.auth = string(decoded)
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			session.from = strings.TrimSuffix(strings.TrimPrefix(arg, "FROM:<"), ">")
+			reply("250 OK")
+		case "RCPT":
+			if s.rejectRcpt != 0 {
+				reply(strconv.Itoa(s.rejectRcpt) + " 5.1.1 Mailbox unavailable")
+				continue
+			}
+			session.to = append(session.to, strings.TrimSuffix(strings.TrimPrefix(arg, "TO:<"), ">"))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			session.data = data.String()
+			reply("250 OK: queued")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// next ждет завершенную сессию
+func (s *fakeSMTPServer) next(t *testing.T) smtpSession {
+	t.Helper()
+	select {
+	case session := <-s.sessions:
+		return session
+	case <-time.After(5 * time.Second):
+		t.Fatal("SMTP server did not receive a session")
+		return smtpSession{}
+	}
+}
+
+// testContactMessage сообщение с именем, которое пытается добавить заголовок
+func testContactMessage() *models.ContactMessage {
+	return &models.ContactMessage{
+		ID:        7,
+		Name:      "Иван\r\nBcc: victim@example.com",
+		Email:     "ivan@example.com",
+		Message:   "Здравствуйте!\nХочу заказать сайт.",
+		Locale:    "ru",
+		IP:        "192.0.2.1",
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestSMTPNotifierSendsMessage(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	notifier, err := NewSMTPNotifier(SMTPOptions{
+		Host:     "127.0.0.1",
+		Port:     server.port(),
+		Username: "user",
+		Password: "secret",
+		From:     "Сайт <noreply@example.com>",
+		To:       []string{"admin@example.com", "Sales <sales@example.com>"},
+	})
+	if err != nil {
+		t.Fatalf("NewSMTPNotifier: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := notifier.Notify(ctx, testContactMessage()); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	session := server.next(t)
+	if session.auth != "\x00user\x00secret" {
+		t.Errorf("AUTH PLAIN = %q, want user/secret", session.auth)
+	}
+	if session.from != "noreply@example.com" {
+		t.Errorf("MAIL FROM = %q, want noreply@example.com", session.from)
+	}
+	if strings.Join(session.to, ",") != "admin@example.com,sales@example.com" {
+		t.Errorf("RCPT TO = %v, want both recipients", session.to)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(session.data))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	// Перевод строки в имени не создает заголовок Bcc
+	if bcc := msg.Header.Get("Bcc"); bcc != "" {
+		t.Errorf("Bcc header = %q, want none", bcc)
+	}
+	replyTo, err := mail.ParseAddress(msg.Header.Get("Reply-To"))
+	if err != nil || replyTo.Address != "ivan@example.com" {
+		t.Errorf("Reply-To = %q, %v; want author address", msg.Header.Get("Reply-To"), err)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	for _, want := range []string{"Новое сообщение #7", "Email: ivan@example.com", "Хочу заказать сайт."} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("body does not contain %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestSMTPNotifierReportsRejectedRecipient(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	server.rejectRcpt = 550
+	notifier, err := NewSMTPNotifier(SMTPOptions{
+		Host: "127.0.0.1",
+		Port: server.port(),
+		From: "noreply@example.com",
+		To:   []string{"missing@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("NewSMTPNotifier: %v", err)
+	}
+
+	err = notifier.Notify(context.Background(), testContactMessage())
+	if err == nil || !strings.Contains(err.Error(), "RCPT TO missing@example.com") {
+		t.Fatalf("Notify() = %v, want RCPT TO error", err)
+	}
+	server.next(t)
+}
+
+func TestSMTPNotifierRespectsContextDeadline(t *testing.T) {
+	// Сервер принимает соединение, но молчит: Notify должен уложиться в таймаут
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer func() {
+				_ = conn.Close()
+			}()
+			_, _ = io.Copy(io.Discard, conn)
+		}
+	}()
+
+	notifier, err := NewSMTPNotifier(SMTPOptions{
+		Host: "127.0.0.1",
+		Port: listener.Addr().(*net.TCPAddr).Port,
+		From: "noreply@example.com",
+		To:   []string{"admin@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("NewSMTPNotifier: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := notifier.Notify(ctx, testContactMessage()); err == nil {
+		t.Fatal("Notify() = nil, want timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("Notify() took %v, want it to stop at the context deadline", elapsed)
+	}
+}
+
+func TestNewSMTPNotifierValidatesOptions(t *testing.T) {
+	valid := SMTPOptions{Host: "smtp.example.com", Port: 587, From: "noreply@example.com", To: []string{"admin@example.com"}}
+	if _, err := NewSMTPNotifier(valid); err != nil {
+		t.Fatalf("NewSMTPNotifier(valid) = %v", err)
+	}
+
+	tests := map[string]func(*SMTPOptions){
+		"no host":       func(o *SMTPOptions) { o.Host = "" },
+		"bad port":      func(o *SMTPOptions) { o.Port = 0 },
+		"bad from":      func(o *SMTPOptions) { o.From = "not an address" },
+		"no recipients": func(o *SMTPOptions) { o.To = nil },
+		"bad recipient": func(o *SMTPOptions) { o.To = []string{"admin@"} },
+	}
+	for name, change := range tests {
+		opts := valid
+		change(&opts)
+		if _, err := NewSMTPNotifier(opts); err == nil {
+			t.Errorf("%s: NewSMTPNotifier() = nil error", name)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"gin-starter/internal/models"
+)
+
+// WebhookNotifier отправляет сообщение POST-запросом с JSON (поля как
+// у models.ContactMessage) - например, во входящий вебхук чата или CRM
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier создает уведомление на адрес rawURL (http или https)
+func NewWebhookNotifier(rawURL string) (*WebhookNotifier, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("webhook: invalid url %q", rawURL)
+	}
+	// Время запроса ограничивает контекст уведомления
+	return &WebhookNotifier{url: rawURL, client: &http.Client{}}, nil
+}
+
+// Notify отправляет сообщение; ответ не из 2xx считается ошибкой
+func (n *WebhookNotifier) Notify(ctx context.Context, message *models.ContactMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("webhook: failed to encode message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook: failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: request failed: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	// Тело дочитывается, чтобы соединение вернулось в пул
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
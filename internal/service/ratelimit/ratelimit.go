@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter ограничивает число событий по ключу (например, IP) в окне
+// фиксированной длины. Состояние хранится в памяти процесса: при нескольких
+// экземплярах сервера лимит действует на каждый отдельно
+type Limiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// bucket события одного ключа в текущем окне
+type bucket struct {
+	start time.Time
+	count int
+}
+
+// New создает ограничитель: не больше limit событий за window.
+// limit <= 0 выключает ограничение
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		limit:     limit,
+		window:    window,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow учитывает событие для ключа. Если лимит исчерпан, событие не
+// учитывается, а вторым значением возвращается время до начала нового окна
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil || l.limit <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok || now.Sub(b.start) >= l.window {
+		b = &bucket{start: now}
+		l.buckets[key] = b
+	}
+	if b.count >= l.limit {
+		return false, b.start.Add(l.window).Sub(now)
+	}
+	b.count++
+	return true, 0
+}
+
+// sweep раз в окно удаляет ключи с истекшим окном, чтобы карта не росла
+// от разовых посетителей
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.start) >= l.window {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
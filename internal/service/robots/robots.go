@@ -0,0 +1,122 @@
+package robots
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Group правила для одного User-agent
+type Group struct {
+	UserAgent  string
+	Allow      []string
+	Disallow   []string
+	CrawlDelay int // секунды; 0 - не указывать
+}
+
+// ParseRules разбирает правила из строки конфига в формате пресетов изображений:
+// группы через ";", правила через ",", например
+//
+//	*=disallow:/api/,disallow:/optimized-image; GPTBot=disallow:/
+//
+// Ключи: allow, disallow (путь от корня, можно с * и $) и crawl-delay (секунды)
+func ParseRules(spec string) ([]Group, error) {
+	var groups []Group
+	seen := make(map[string]bool)
+
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		agent, params, ok := strings.Cut(item, "=")
+		agent = strings.TrimSpace(agent)
+		if !ok || agent == "" {
+			return nil, fmt.Errorf("robots rule %q: expected user-agent=rules", item)
+		}
+		if seen[strings.ToLower(agent)] {
+			return nil, fmt.Errorf("robots rule %q: user-agent defined twice", agent)
+		}
+		seen[strings.ToLower(agent)] = true
+
+		group := Group{UserAgent: agent}
+		for _, param := range strings.Split(params, ",") {
+			param = strings.TrimSpace(param)
+			if param == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(param, ":")
+			if !ok {
+				return nil, fmt.Errorf("robots rule %q: expected key:value, got %q", agent, param)
+			}
+			key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+			switch key {
+			case "allow", "disallow":
+				if !strings.HasPrefix(value, "/") && !strings.HasPrefix(value, "*") {
+					return nil, fmt.Errorf("robots rule %q: path must start with / or *, got %q", agent, value)
+				}
+				if key == "allow" {
+					group.Allow = append(group.Allow, value)
+				} else {
+					group.Disallow = append(group.Disallow, value)
+				}
+			case "crawl-delay":
+				delay, err := strconv.Atoi(value)
+				if err != nil || delay < 0 {
+					return nil, fmt.Errorf("robots rule %q: invalid crawl-delay %q", agent, value)
+				}
+				group.CrawlDelay = delay
+			default:
+				return nil, fmt.Errorf("robots rule %q: unknown key %q", agent, key)
+			}
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+// Write пишет robots.txt. Если indexable == false (не production), правила
+// конфига не используются: всем роботам запрещен весь сайт. sitemapURL должен
+// быть абсолютным - относительный адрес поисковики игнорируют
+func Write(w io.Writer, groups []Group, sitemapURL string, indexable bool) error {
+	var b strings.Builder
+
+	if !indexable {
+		b.WriteString("User-agent: *\nDisallow: /\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	if len(groups) == 0 {
+		groups = []Group{{UserAgent: "*"}}
+	}
+	for i, group := range groups {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "User-agent: %s\n", group.UserAgent)
+		for _, path := range group.Allow {
+			fmt.Fprintf(&b, "Allow: %s\n", path)
+		}
+		for _, path := range group.Disallow {
+			fmt.Fprintf(&b, "Disallow: %s\n", path)
+		}
+		if len(group.Allow) == 0 && len(group.Disallow) == 0 {
+			// Группа без правил недопустима: пустой Disallow разрешает все
+			b.WriteString("Disallow:\n")
+		}
+		if group.CrawlDelay > 0 {
+			fmt.Fprintf(&b, "Crawl-delay: %d\n", group.CrawlDelay)
+		}
+	}
+	if sitemapURL != "" {
+		fmt.Fprintf(&b, "\nSitemap: %s\n", sitemapURL)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,269 @@
+package sitemap
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxURLsPerFile предел протокола sitemaps.org на один файл; больше - нужен индекс
+const MaxURLsPerFile = 50000
+
+// ChangeFreq как часто меняется страница (подсказка для поисковиков)
+type ChangeFreq string
+
+const (
+	Always  ChangeFreq = "always"
+	Hourly  ChangeFreq = "hourly"
+	Daily   ChangeFreq = "daily"
+	Weekly  ChangeFreq = "weekly"
+	Monthly ChangeFreq = "monthly"
+	Yearly  ChangeFreq = "yearly"
+	Never   ChangeFreq = "never"
+)
+
+// Page метаданные страницы для карты сайта, задаются вместе с маршрутом
+type Page struct {
+	ChangeFreq ChangeFreq
+	Priority   float64 // 0-1; 0 - не указывать (поисковики считают 0.5)
+	// LastMod время изменения; пусто - время сборки приложения (страница
+	// меняется только вместе с кодом шаблонов)
+	LastMod time.Time
+}
+
+// Entry одна ссылка карты сайта. Loc - путь от корня сайта ("/about")
+type Entry struct {
+	Loc        string
+	LastMod    time.Time
+	ChangeFreq ChangeFreq
+	Priority   float64
+}
+
+// Source источник динамических ссылок: записи, товары и т.п. Запись с путем
+// уже зарегистрированной страницы не дублирует ее, а обновляет lastmod
+// (например, список пользователей меняется вместе с последним пользователем)
+type Source interface {
+	Entries(ctx context.Context) ([]Entry, error)
+}
+
+// SourceFunc функция как Source
+type SourceFunc func(ctx context.Context) ([]Entry, error)
+
+// Entries вызывает f
+func (f SourceFunc) Entries(ctx context.Context) ([]Entry, error) {
+	return f(ctx)
+}
+
+// Sitemap реестр страниц и динамических источников карты сайта
+type Sitemap struct {
+	buildTime time.Time
+	cacheTTL  time.Duration
+
+	mutex   sync.Mutex
+	pages   map[string]Page
+	sources []Source
+	// generation меняется при регистрации страниц и источников: сборка,
+	// начатая до изменения, не попадает в кэш
+	generation uint64
+
+	cached   []Entry
+	cachedAt time.Time
+}
+
+// New создает карту сайта. Собранный список ссылок кэшируется на cacheTTL,
+// чтобы частые запросы краулеров не ходили каждый раз в базу
+func New(cacheTTL time.Duration) *Sitemap {
+	return &Sitemap{
+		buildTime: buildTime(),
+		cacheTTL:  cacheTTL,
+		pages:     make(map[string]Page),
+	}
+}
+
+// AddPage регистрирует страницу по пути маршрута
+func (s *Sitemap) AddPage(path string, page Page) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.pages[path] = page
+	s.invalidate()
+}
+
+// AddSource подключает источник динамических ссылок
+func (s *Sitemap) AddSource(source Source) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sources = append(s.sources, source)
+	s.invalidate()
+}
+
+// invalidate сбрасывает кэш; вызывается под мьютексом
+func (s *Sitemap) invalidate() {
+	s.generation++
+	s.cachedAt = time.Time{}
+}
+
+// Entries возвращает все ссылки, отсортированные по пути. Ошибка источника
+// не ломает карту: его ссылки пропускаются до следующей сборки. Источники
+// опрашиваются без мьютекса: медленный запрос к базе не должен держать
+// регистрацию страниц и запросы, которым хватает кэша
+func (s *Sitemap) Entries(ctx context.Context) []Entry {
+	s.mutex.Lock()
+	if !s.cachedAt.IsZero() && time.Since(s.cachedAt) < s.cacheTTL {
+		cached := s.cached
+		s.mutex.Unlock()
+		return cached
+	}
+	generation := s.generation
+	byLoc := make(map[string]Entry, len(s.pages))
+	for path, page := range s.pages {
+		lastMod := page.LastMod
+		if lastMod.IsZero() {
+			lastMod = s.buildTime
+		}
+		byLoc[path] = Entry{Loc: path, LastMod: lastMod, ChangeFreq: page.ChangeFreq, Priority: page.Priority}
+	}
+	sources := append([]Source(nil), s.sources...)
+	s.mutex.Unlock()
+
+	for _, source := range sources {
+		entries, err := source.Entries(ctx)
+		if err != nil {
+			log.Printf("Sitemap source error: %v", err)
+			continue
+		}
+		for _, entry := range entries {
+			existing, ok := byLoc[entry.Loc]
+			if !ok {
+				byLoc[entry.Loc] = entry
+				continue
+			}
+			if entry.LastMod.After(existing.LastMod) {
+				existing.LastMod = entry.LastMod
+			}
+			byLoc[entry.Loc] = existing
+		}
+	}
+
+	entries := make([]Entry, 0, len(byLoc))
+	for _, entry := range byLoc {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Loc < entries[j].Loc })
+
+	s.mutex.Lock()
+	if s.generation == generation {
+		s.cached, s.cachedAt = entries, time.Now()
+	}
+	s.mutex.Unlock()
+	return entries
+}
+
+// Chunks делит ссылки на файлы по MaxURLsPerFile
+func Chunks(entries []Entry) [][]Entry {
+	var chunks [][]Entry
+	for len(entries) > MaxURLsPerFile {
+		chunks = append(chunks, entries[:MaxURLsPerFile])
+		entries = entries[MaxURLsPerFile:]
+	}
+	return append(chunks, entries)
+}
+
+// LastModified самое позднее время изменения среди ссылок
+func LastModified(entries []Entry) time.Time {
+	var latest time.Time
+	for _, entry := range entries {
+		if entry.LastMod.After(latest) {
+			latest = entry.LastMod
+		}
+	}
+	return latest
+}
+
+// WriteURLSet пишет файл карты сайта. baseURL - публичный адрес сайта без "/" в конце
+func WriteURLSet(w io.Writer, baseURL string, entries []Entry) error {
+	if _, err := io.WriteString(w, xml.Header+`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`+"\n"); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		var b strings.Builder
+		b.WriteString("  <url>\n    <loc>")
+		if err := xml.EscapeText(&b, []byte(baseURL+entry.Loc)); err != nil {
+			return err
+		}
+		b.WriteString("</loc>\n")
+		if !entry.LastMod.IsZero() {
+			fmt.Fprintf(&b, "    <lastmod>%s</lastmod>\n", entry.LastMod.UTC().Format(time.RFC3339))
+		}
+		if entry.ChangeFreq != "" {
+			fmt.Fprintf(&b, "    <changefreq>%s</changefreq>\n", entry.ChangeFreq)
+		}
+		if entry.Priority > 0 {
+			fmt.Fprintf(&b, "    <priority>%s</priority>\n", strconv.FormatFloat(entry.Priority, 'f', 1, 64))
+		}
+		b.WriteString("  </url>\n")
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "</urlset>\n")
+	return err
+}
+
+// IndexEntry ссылка на файл карты сайта в индексе
+type IndexEntry struct {
+	Loc     string // абсолютный адрес файла
+	LastMod time.Time
+}
+
+// WriteIndex пишет индекс карт сайта
+func WriteIndex(w io.Writer, entries []IndexEntry) error {
+	if _, err := io.WriteString(w, xml.Header+`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`+"\n"); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		var b strings.Builder
+		b.WriteString("  <sitemap>\n    <loc>")
+		if err := xml.EscapeText(&b, []byte(entry.Loc)); err != nil {
+			return err
+		}
+		b.WriteString("</loc>\n")
+		if !entry.LastMod.IsZero() {
+			fmt.Fprintf(&b, "    <lastmod>%s</lastmod>\n", entry.LastMod.UTC().Format(time.RFC3339))
+		}
+		b.WriteString("  </sitemap>\n")
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "</sitemapindex>\n")
+	return err
+}
+
+// buildTime время последнего коммита из сведений о сборке (go build в git-репозитории),
+// иначе время изменения исполняемого файла, иначе время запуска
+func buildTime() time.Time {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.time" {
+				if t, err := time.Parse(time.RFC3339, setting.Value); err == nil {
+					return t
+				}
+			}
+		}
+	}
+	if exe, err := os.Executable(); err == nil {
+		if stat, err := os.Stat(exe); err == nil {
+			return stat.ModTime()
+		}
+	}
+	return time.Now()
+}
@@ -0,0 +1,149 @@
+package sitemap
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEntriesMergesPagesAndSources(t *testing.T) {
+	s := New(time.Hour)
+	s.AddPage("/", Page{ChangeFreq: Weekly, Priority: 1, LastMod: time.Unix(100, 0)})
+	s.AddPage("/about", Page{ChangeFreq: Monthly})
+
+	updated := time.Unix(200, 0)
+	s.AddSource(SourceFunc(func(context.Context) ([]Entry, error) {
+		return []Entry{
+			{Loc: "/", LastMod: updated},
+			{Loc: "/users", LastMod: updated, ChangeFreq: Daily, Priority: 0.6},
+		}, nil
+	}))
+	s.AddSource(SourceFunc(func(context.Context) ([]Entry, error) {
+		return nil, errors.New("database is down")
+	}))
+
+	entries := s.Entries(context.Background())
+	if len(entries) != 3 {
+		t.Fatalf("Entries() = %+v, want 3 entries", entries)
+	}
+	if entries[0].Loc != "/" || !entries[0].LastMod.Equal(updated) || entries[0].ChangeFreq != Weekly {
+		t.Errorf("entries[0] = %+v, want / with source lastmod and page changefreq", entries[0])
+	}
+	if entries[1].Loc != "/about" || !entries[1].LastMod.Equal(s.buildTime) {
+		t.Errorf("entries[1] = %+v, want /about with build time", entries[1])
+	}
+	if entries[2].Loc != "/users" || entries[2].ChangeFreq != Daily {
+		t.Errorf("entries[2] = %+v, want /users from source", entries[2])
+	}
+}
+
+func TestEntriesCachesUntilChanged(t *testing.T) {
+	s := New(time.Hour)
+	calls := 0
+	s.AddSource(SourceFunc(func(context.Context) ([]Entry, error) {
+		calls++
+		return []Entry{{Loc: "/dynamic"}}, nil
+	}))
+
+	s.Entries(context.Background())
+	s.Entries(context.Background())
+	if calls != 1 {
+		t.Fatalf("source queried %d times within TTL, want 1", calls)
+	}
+
+	s.AddPage("/new", Page{})
+	if entries := s.Entries(context.Background()); len(entries) != 2 || calls != 2 {
+		t.Fatalf("after AddPage: %d entries, %d source calls; want 2 and 2", len(entries), calls)
+	}
+}
+
+func TestEntriesQueriesSourcesWithoutLock(t *testing.T) {
+	s := New(time.Hour)
+	s.AddPage("/", Page{})
+
+	// Источник, который сам обращается к карте сайта, не должен ее блокировать
+	s.AddSource(SourceFunc(func(context.Context) ([]Entry, error) {
+		s.AddPage("/late", Page{})
+		return nil, nil
+	}))
+
+	done := make(chan []Entry, 1)
+	go func() {
+		done <- s.Entries(context.Background())
+	}()
+
+	select {
+	case entries := <-done:
+		if len(entries) != 1 {
+			t.Fatalf("Entries() = %+v, want only the page registered before the build", entries)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Entries() deadlocked while querying a source")
+	}
+
+	// Сборка, начатая до AddPage, в кэш не попала: новая страница видна сразу
+	if entries := s.Entries(context.Background()); len(entries) != 2 {
+		t.Fatalf("Entries() after concurrent AddPage = %+v, want 2 entries", entries)
+	}
+}
+
+func TestChunksSplitsByLimit(t *testing.T) {
+	entries := make([]Entry, MaxURLsPerFile+1)
+	chunks := Chunks(entries)
+	if len(chunks) != 2 || len(chunks[0]) != MaxURLsPerFile || len(chunks[1]) != 1 {
+		t.Fatalf("Chunks(%d) sizes = %d chunks, want %d and 1", len(entries), len(chunks), MaxURLsPerFile)
+	}
+	if chunks := Chunks(nil); len(chunks) != 1 || len(chunks[0]) != 0 {
+		t.Fatalf("Chunks(nil) = %v, want one empty file", chunks)
+	}
+}
+
+func TestWriteURLSet(t *testing.T) {
+	var buf strings.Builder
+	err := WriteURLSet(&buf, "https://example.com", []Entry{
+		{Loc: "/", LastMod: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), ChangeFreq: Weekly, Priority: 1},
+		{Loc: "/search?q=a&b"},
+	})
+	if err != nil {
+		t.Fatalf("WriteURLSet: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`,
+		"<loc>https://example.com/</loc>",
+		"<lastmod>2024-05-01T12:00:00Z</lastmod>",
+		"<changefreq>weekly</changefreq>",
+		"<priority>1.0</priority>",
+		"<loc>https://example.com/search?q=a&amp;b</loc>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("urlset does not contain %s\n%s", want, out)
+		}
+	}
+	// Пустые поля не выводятся
+	if strings.Count(out, "<lastmod>") != 1 || strings.Count(out, "<priority>") != 1 {
+		t.Errorf("urlset renders empty fields\n%s", out)
+	}
+}
+
+func TestWriteIndex(t *testing.T) {
+	var buf strings.Builder
+	err := WriteIndex(&buf, []IndexEntry{
+		{Loc: "https://example.com/sitemap-1.xml", LastMod: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{Loc: "https://example.com/sitemap-2.xml"},
+	})
+	if err != nil {
+		t.Fatalf("WriteIndex: %v", err)
+	}
+
+	out := buf.String()
+	if strings.Count(out, "<sitemap>") != 2 || strings.Count(out, "<lastmod>") != 1 {
+		t.Errorf("index = %s, want two files and one lastmod", out)
+	}
+	if !strings.Contains(out, "<loc>https://example.com/sitemap-2.xml</loc>") {
+		t.Errorf("index does not list sitemap-2.xml\n%s", out)
+	}
+}
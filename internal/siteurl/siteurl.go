@@ -0,0 +1,122 @@
+package siteurl
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Options правила построения публичных адресов
+type Options struct {
+	// BaseURL публичный адрес сайта (https://example.com). Пусто - схема и хост
+	// берутся из запроса с учетом X-Forwarded-Proto и X-Forwarded-Host; этим
+	// заголовкам можно верить только за своим прокси, поэтому в production
+	// адрес лучше задать явно
+	BaseURL string
+	// CanonicalParams параметры запроса, которые меняют содержимое страницы
+	// (page, q) и остаются в canonical; остальные (utm_*, сортировки и т.п.) отбрасываются
+	CanonicalParams []string
+}
+
+// Resolver строит абсолютные и канонические адреса страниц
+type Resolver struct {
+	baseURL         string // без "/" в конце; пусто - из запроса
+	canonicalParams map[string]bool
+}
+
+// Default резолвер без настроек: адрес из запроса, canonical без параметров
+var Default = &Resolver{canonicalParams: map[string]bool{}}
+
+// New создает резолвер. BaseURL должен быть абсолютным http(s)-адресом без
+// параметров: ошибка в нем испортила бы все canonical и sitemap
+func New(opts Options) (*Resolver, error) {
+	r := &Resolver{canonicalParams: make(map[string]bool, len(opts.CanonicalParams))}
+	for _, param := range opts.CanonicalParams {
+		r.canonicalParams[param] = true
+	}
+
+	if opts.BaseURL == "" {
+		return r, nil
+	}
+	base, err := url.Parse(opts.BaseURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("base URL must be an absolute http(s) URL, got %q", opts.BaseURL)
+	}
+	if base.RawQuery != "" || base.Fragment != "" {
+		return nil, fmt.Errorf("base URL must not have a query or fragment, got %q", opts.BaseURL)
+	}
+	r.baseURL = strings.TrimRight(base.Scheme+"://"+base.Host+base.Path, "/")
+	return r, nil
+}
+
+// BaseURL публичный адрес сайта без "/" в конце
+func (r *Resolver) BaseURL(req *http.Request) string {
+	if r.baseURL != "" {
+		return r.baseURL
+	}
+
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	if proto := firstValue(req.Header.Get("X-Forwarded-Proto")); proto == "https" || proto == "http" {
+		scheme = proto
+	}
+	host := req.Host
+	if forwarded := firstValue(req.Header.Get("X-Forwarded-Host")); forwarded != "" {
+		host = forwarded
+	}
+	return scheme + "://" + host
+}
+
+// Absolute абсолютный адрес пути от корня сайта ("/about")
+func (r *Resolver) Absolute(req *http.Request, p string) string {
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return r.BaseURL(req) + p
+}
+
+// Canonical канонический адрес запрошенной страницы: нормализованный путь
+// без "/" в конце и только значимые параметры в постоянном порядке
+func (r *Resolver) Canonical(req *http.Request) string {
+	canonical := r.Absolute(req, CleanPath(req.URL.Path))
+	if query := r.CanonicalQuery(req.URL.Query()); query != "" {
+		canonical += "?" + query
+	}
+	return canonical
+}
+
+// CanonicalQuery оставляет только параметры из CanonicalParams с непустыми
+// значениями; url.Values.Encode сортирует их по имени
+func (r *Resolver) CanonicalQuery(query url.Values) string {
+	kept := url.Values{}
+	for name, values := range query {
+		if !r.canonicalParams[name] {
+			continue
+		}
+		for _, value := range values {
+			if value != "" {
+				kept.Add(name, value)
+			}
+		}
+	}
+	return kept.Encode()
+}
+
+// CleanPath нормализует путь: схлопывает "//", убирает "." и ".." и "/" в конце
+// (кроме корня)
+func CleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return path.Clean("/" + p)
+}
+
+// firstValue первое значение из списка через запятую (заголовок после нескольких прокси)
+func firstValue(header string) string {
+	value, _, _ := strings.Cut(header, ",")
+	return strings.ToLower(strings.TrimSpace(value))
+}
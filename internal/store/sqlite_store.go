@@ -12,8 +12,10 @@ import (
 
 // SQLiteStore структура для хранения подключений к SQLite
 type SQLiteStore struct {
-	DB       *sql.DB
-	UserRepo repository.UserRepository
+	DB          *sql.DB
+	UserRepo    repository.UserRepository
+	MediaRepo   repository.MediaRepository
+	ContactRepo repository.ContactMessageRepository
 }
 
 // NewSQLiteStore создает новый экземпляр SQLiteStore
@@ -29,6 +31,8 @@ func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
 
 	// Инициализируем репозитории
 	store.UserRepo = repository.NewSQLiteUserRepository(db)
+	store.MediaRepo = repository.NewSQLiteMediaRepository(db)
+	store.ContactRepo = repository.NewSQLiteContactMessageRepository(db)
 
 	// Создаем таблицы
 	if err := store.createTables(); err != nil {
@@ -49,6 +53,31 @@ func (s *SQLiteStore) createTables() error {
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_users_email ON users(email)`,
+		`CREATE TABLE IF NOT EXISTS media (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			storage_key TEXT NOT NULL,
+			url TEXT NOT NULL,
+			content_type TEXT NOT NULL,
+			size INTEGER NOT NULL,
+			width INTEGER NOT NULL,
+			height INTEGER NOT NULL,
+			alt_text TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_media_owner ON media(owner_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_media_storage_key ON media(storage_key)`,
+		`CREATE TABLE IF NOT EXISTS contact_messages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			email TEXT NOT NULL,
+			message TEXT NOT NULL,
+			ip TEXT NOT NULL DEFAULT '',
+			user_agent TEXT NOT NULL DEFAULT '',
+			locale TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_contact_messages_created ON contact_messages(created_at)`,
 	}
 
 	for _, query := range queries {
@@ -88,3 +117,13 @@ func (s *SQLiteStore) Migrate() error {
 func (s *SQLiteStore) GetUserRepo() repository.UserRepository {
 	return s.UserRepo
 }
+
+// GetMediaRepo возвращает репозиторий загруженных файлов
+func (s *SQLiteStore) GetMediaRepo() repository.MediaRepository {
+	return s.MediaRepo
+}
+
+// GetContactRepo возвращает репозиторий сообщений обратной связи
+func (s *SQLiteStore) GetContactRepo() repository.ContactMessageRepository {
+	return s.ContactRepo
+}
@@ -13,12 +13,18 @@ type Store interface {
 	Migrate() error
 	// Методы для работы с пользователями
 	GetUserRepo() repository.UserRepository
+	// Методы для работы с загруженными файлами
+	GetMediaRepo() repository.MediaRepository
+	// Методы для работы с сообщениями формы обратной связи
+	GetContactRepo() repository.ContactMessageRepository
 }
 
 // PostgreSQLStore структура для хранения подключений к PostgreSQL
 type PostgreSQLStore struct {
-	DB       *sql.DB
-	UserRepo repository.UserRepository
+	DB          *sql.DB
+	UserRepo    repository.UserRepository
+	MediaRepo   repository.MediaRepository
+	ContactRepo repository.ContactMessageRepository
 }
 
 // NewPostgreSQLStore создает новый экземпляр PostgreSQLStore
@@ -38,7 +44,9 @@ func NewPostgreSQLStore(dbHost, dbPort, dbUser, dbPassword, dbName string) (*Pos
 	store := &PostgreSQLStore{
 		DB: db,
 		// Заглушка для репозитория пользователей - будет реализована при необходимости
-		UserRepo: nil,
+		UserRepo:    nil,
+		MediaRepo:   nil,
+		ContactRepo: nil,
 	}
 
 	return store, nil
@@ -65,3 +73,15 @@ func (s *PostgreSQLStore) GetUserRepo() repository.UserRepository {
 	// Пока возвращаем nil, в продакшене нужно будет реализовать
 	return s.UserRepo
 }
+
+// GetMediaRepo возвращает репозиторий загруженных файлов
+func (s *PostgreSQLStore) GetMediaRepo() repository.MediaRepository {
+	// TODO: Реализовать PostgreSQL репозиторий файлов
+	return s.MediaRepo
+}
+
+// GetContactRepo возвращает репозиторий сообщений обратной связи
+func (s *PostgreSQLStore) GetContactRepo() repository.ContactMessageRepository {
+	// TODO: Реализовать PostgreSQL репозиторий сообщений
+	return s.ContactRepo
+}
@@ -0,0 +1,27 @@
+package testutil
+
+import (
+	"context"
+
+	"gin-starter/internal/service/image"
+)
+
+// ImageVersion версия исходника, которую отдает ImageService
+const ImageVersion = "v1"
+
+// ImageService фейковый сервис изображений для шаблонов: любой исходник имеет
+// заданные размеры и версию ImageVersion, превью пустое
+type ImageService struct {
+	Width, Height int
+	Animated      bool
+}
+
+// SourceInfo возвращает заданные сведения об исходнике
+func (s ImageService) SourceInfo(string) (*image.SourceInfo, error) {
+	return &image.SourceInfo{Width: s.Width, Height: s.Height, Version: ImageVersion, Animated: s.Animated}, nil
+}
+
+// Placeholder возвращает пустое превью
+func (ImageService) Placeholder(context.Context, string) (*image.Placeholder, error) {
+	return &image.Placeholder{}, nil
+}
@@ -0,0 +1,36 @@
+package testutil
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gin-starter/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewSQLiteStore открывает базу SQLite во временном каталоге теста;
+// база закрывается вместе с тестом
+func NewSQLiteStore(t *testing.T) *store.SQLiteStore {
+	t.Helper()
+	dbStore, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = dbStore.Close()
+	})
+	return dbStore
+}
+
+// NewRouter роутер gin в тестовом режиме, который, как и cmd/server,
+// кладет dbStore в контекст запроса
+func NewRouter(dbStore *store.SQLiteStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("dbStore", dbStore)
+		c.Next()
+	})
+	return router
+}
@@ -2,8 +2,22 @@
 document.addEventListener("DOMContentLoaded", function () {
   console.log("Gin приложение загружено!");
 
+  // Изображения с LQIP-превью: фон-заглушка убирается, когда загрузилась полная картинка
+  document.querySelectorAll("img[data-placeholder]").forEach((img) => {
+    const clearPlaceholder = () => {
+      img.style.backgroundImage = "";
+      img.removeAttribute("data-placeholder");
+    };
+    if (img.complete && img.naturalWidth > 0) {
+      clearPlaceholder();
+    } else {
+      img.addEventListener("load", clearPlaceholder, { once: true });
+    }
+  });
+
   // Анимация появления изображения на главной странице
-  const imageElements = document.querySelectorAll('img[src*="optimized-image"]');
+  // (картинки с превью не скрываем, иначе пропадет и сама заглушка)
+  const imageElements = document.querySelectorAll('img[src*="optimized-image"]:not([data-placeholder])');
   imageElements.forEach((img, index) => {
     // Устанавливаем начальное состояние
     img.style.opacity = "0";
@@ -18,85 +32,33 @@ document.addEventListener("DOMContentLoaded", function () {
   });
 });
 
-// Функция для работы со страницей пользователей
-function userData() {
+// Функция для работы со страницей пользователей. Список, поиск, пагинация и
+// формы работают на сервере, а htmx подменяет только фрагменты. Здесь - показ
+// формы и модальные окна по событиям из заголовка HX-Trigger (user-created,
+// user-deleted). Тексты приходят с сервера на языке страницы (templates/pages/users.go)
+function userData(messages = {}, showForm = false) {
 	return {
-		users: [],
-		loading: false,
-		showAddForm: false,
+		messages: messages,
+		showAddForm: showForm,
 		showConfirmationModal: false,
 		showSuccessModal: false,
 		showErrorModal: false,
-		modalUserId: null,
+		modalForm: null,
 		modalTitle: '',
 		modalMessage: '',
 		successMessage: '',
 		errorMessage: '',
-		newUser: {
-			name: '',
-			email: ''
-		},
-		addingUser: false,
 		showAddSuccessModal: false,
 		addSuccessMessage: '',
 
-		fetchUsers() {
-			this.loading = true;
-			fetch('/api/users')
-				.then(response => response.json())
-				.then(data => {
-					this.users = data;
-				})
-				.catch(error => {
-					console.error('Error fetching users:', error);
-					alert('Ошибка при загрузке пользователей');
-				})
-				.finally(() => {
-					this.loading = false;
-				});
-		},
-
-		addUser() {
-			if (!this.newUser.name || !this.newUser.email) {
-				// Показываем окно с ошибкой
-				this.showErrorModal = true;
-				this.errorMessage = 'Пожалуйста, заполните все поля';
-				return;
-			}
-
-			this.addingUser = true;
-			fetch('/api/users', {
-				method: 'POST',
-				headers: {
-					'Content-Type': 'application/json'
-				},
-				body: JSON.stringify(this.newUser)
-			})
-			.then(response => {
-				if (response.ok) {
-					return response.json();
-				}
-				throw new Error('Network response was not ok');
-			})
-			.then(newUser => {
-				// Добавляем нового пользователя в конец списка
-				this.users.push(newUser);
-				// Очищаем форму
-				this.newUser = { name: '', email: '' };
-				this.showAddForm = false;
-				// Показываем окно об успешном добавлении
-				this.showAddSuccessModal = true;
-				this.addSuccessMessage = 'Пользователь успешно добавлен!';
-			})
-			.catch(error => {
-				console.error('Error adding user:', error);
-				// Показываем окно с ошибкой
-				this.showErrorModal = true;
-				this.errorMessage = 'Ошибка при добавлении пользователя';
-			})
-			.finally(() => {
-				this.addingUser = false;
-			});
+		onUserCreated() {
+			// Очищаем форму
+			this.$refs.form.reset();
+			document.getElementById('user-form-error').textContent = '';
+			this.showAddForm = false;
+			// Показываем окно об успешном добавлении
+			this.showAddSuccessModal = true;
+			this.addSuccessMessage = this.t('added');
 		},
 
 		closeAddSuccessModal() {
@@ -104,48 +66,50 @@ function userData() {
 			this.addSuccessMessage = '';
 		},
 
-		showDeleteConfirmation(userId, userName) {
-			this.modalUserId = userId;
-			this.modalTitle = 'Подтверждение удаления';
-			this.modalMessage = `Вы уверены, что хотите удалить пользователя "${userName}"?`;
+		showDeleteConfirmation(form, userName) {
+			this.modalForm = form;
+			this.modalTitle = this.t('confirmTitle');
+			this.modalMessage = this.t('confirmMessage').replace('{name}', userName);
 			this.showConfirmationModal = true;
 		},
 
 		cancelDeletion() {
 			this.showConfirmationModal = false;
-			this.modalUserId = null;
+			this.modalForm = null;
 		},
 
 		confirmDeletion() {
-			if (!this.modalUserId) {
+			const form = this.modalForm;
+			if (!form) {
 				return;
 			}
+			this.showConfirmationModal = false;
+			this.modalForm = null;
 
-			fetch(`/api/users/${this.modalUserId}`, {
-				method: 'DELETE'
-			})
-			.then(response => {
-				if (response.ok) {
-					// Удаляем пользователя из списка
-					this.users = this.users.filter(user => user.id !== this.modalUserId);
-					this.showConfirmationModal = false;
-					this.modalUserId = null;
-					// Показываем окно об успешном удалении
-					this.showSuccessModal = true;
-					this.successMessage = 'Пользователь успешно удален!';
-				} else {
-					throw new Error('Network response was not ok');
-				}
-			})
-			.catch(error => {
-				console.error('Error deleting user:', error);
-				// Показываем окно с ошибкой
-				this.showConfirmationModal = false;
-				this.showErrorModal = true;
-				this.errorMessage = 'Ошибка при удалении пользователя';
+			// Без htmx (не загрузился CDN) форма уходит обычным запросом
+			if (!window.htmx) {
+				form.submit();
+				return;
+			}
+			htmx.ajax('POST', form.action, {
+				source: form,
+				target: form.closest('tr'),
+				swap: 'outerHTML'
 			});
 		},
 
+		onUserDeleted() {
+			// Показываем окно об успешном удалении
+			this.showSuccessModal = true;
+			this.successMessage = this.t('deleted');
+		},
+
+		onRequestFailed() {
+			// Показываем окно с ошибкой
+			this.showErrorModal = true;
+			this.errorMessage = this.t('requestFailed');
+		},
+
 		closeSuccessModal() {
 			this.showSuccessModal = false;
 			this.successMessage = '';
@@ -154,6 +118,11 @@ function userData() {
 		closeErrorModal() {
 			this.showErrorModal = false;
 			this.errorMessage = '';
+		},
+
+		// t текст по ключу; без перевода возвращает сам ключ
+		t(key) {
+			return this.messages[key] || key;
 		}
 	};
 }
@@ -0,0 +1,228 @@
+package components
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gin-starter/internal/service/image"
+)
+
+// ImageService то, что компонентам нужно от сервиса изображений
+type ImageService interface {
+	SourceInfo(path string) (*image.SourceInfo, error)
+	Placeholder(ctx context.Context, path string) (*image.Placeholder, error)
+}
+
+// Images зависимости компонентов изображений. Передаются через контекст
+// рендеринга (см. WithImages): без них ссылки строятся без версий, размеров и пресетов
+type Images struct {
+	Service ImageService
+	Presets *image.Presets
+}
+
+// imagesKey ключ Images в контексте
+type imagesKey struct{}
+
+// WithImages кладет зависимости компонентов изображений в контекст рендеринга
+func WithImages(ctx context.Context, images Images) context.Context {
+	return context.WithValue(ctx, imagesKey{}, images)
+}
+
+// imagesFrom достает зависимости из контекста; пусто, если их не передали
+func imagesFrom(ctx context.Context) Images {
+	images, _ := ctx.Value(imagesKey{}).(Images)
+	return images
+}
+
+// ImageOptions необязательные параметры адаптивного изображения
+type ImageOptions struct {
+	Class   string
+	Quality int // 0 - качество по умолчанию на сервере
+	// Format формат вариантов. Пусто - <picture> с AVIF и WebP и <img> в JPEG
+	// (анимированный GIF остается GIF); заданный формат рендерится одним <img>
+	Format      image.Format
+	Placeholder bool // показывать размытое LQIP-превью до загрузки
+	Eager       bool // грузить сразу, а не лениво (для первого экрана)
+}
+
+// pictureSource вариант изображения в другом формате для <source>
+type pictureSource struct {
+	Type   string
+	SrcSet string
+}
+
+// responsiveImage подготовленные данные для рендеринга <picture> и <img>
+type responsiveImage struct {
+	Src         string
+	SrcSet      string
+	Sources     []pictureSource // пусто - рендерится один <img> без <picture>
+	Width       int
+	Height      int
+	Placeholder string
+}
+
+// newResponsiveImage вычисляет srcset в формате по умолчанию и в форматах
+// image.PictureFormats, а также собственные размеры картинки, чтобы браузер
+// зарезервировал место заранее
+func newResponsiveImage(ctx context.Context, path string, widths []int, opts ImageOptions) responsiveImage {
+	images := imagesFrom(ctx)
+
+	var srcWidth, srcHeight int
+	var version string
+	var animated bool
+	if images.Service != nil {
+		info, err := images.Service.SourceInfo(path)
+		if err != nil {
+			log.Printf("Responsive image %s: %v", path, err)
+		} else {
+			srcWidth, srcHeight, version, animated = info.Width, info.Height, info.Version, info.Animated
+		}
+	}
+
+	requested := len(widths) > 0
+	widths = fitWidths(widths, srcWidth)
+
+	// Другие форматы предлагаются, только если формат не задан явно. Анимированный
+	// GIF остается одним <img>: в WebP и AVIF сервис сохраняет только первый кадр
+	// Браузер берет первый поддерживаемый <source>, остальные получают <img>
+	formats := image.PictureFormats
+	if opts.Format != image.FormatDefault || animated {
+		formats = nil
+	}
+
+	var img responsiveImage
+	switch {
+	case len(widths) > 0:
+		img.SrcSet = buildSrcSet(images.Presets, path, version, widths, opts.Quality, opts.Format)
+		for _, format := range formats {
+			img.Sources = append(img.Sources, pictureSource{
+				Type:   format.ContentType(),
+				SrcSet: buildSrcSet(images.Presets, path, version, widths, opts.Quality, format),
+			})
+		}
+		largest := widths[len(widths)-1]
+		img.Src = variantURL(images.Presets, path, image.ProcessOptions{Width: largest, Quality: opts.Quality, Format: opts.Format}, version)
+		if srcWidth > 0 {
+			img.Width = largest
+			img.Height = (srcHeight*largest + srcWidth/2) / srcWidth
+		}
+	case requested && srcWidth > 0:
+		// Исходник уже всех запрошенных ширин: отдаем сам файл из /static/.
+		// Вариант его ширины не совпал бы ни с одним пресетом
+		img.Src = path
+		img.Width, img.Height = srcWidth, srcHeight
+	default:
+		img.Src = variantURL(images.Presets, path, image.ProcessOptions{Quality: opts.Quality, Format: opts.Format}, version)
+		for _, format := range formats {
+			img.Sources = append(img.Sources, pictureSource{
+				Type:   format.ContentType(),
+				SrcSet: variantURL(images.Presets, path, image.ProcessOptions{Quality: opts.Quality, Format: format}, version),
+			})
+		}
+	}
+
+	if opts.Placeholder && images.Service != nil {
+		placeholder, err := images.Service.Placeholder(ctx, path)
+		if err != nil {
+			log.Printf("Image placeholder %s: %v", path, err)
+		} else {
+			img.Placeholder = placeholder.DataURI
+		}
+	}
+
+	return img
+}
+
+// fitWidths сортирует ширины и отбрасывает те, что больше исходника:
+// увеличивать картинку бессмысленно, это только лишний трафик
+func fitWidths(widths []int, srcWidth int) []int {
+	result := make([]int, 0, len(widths))
+	for _, w := range widths {
+		if w <= 0 || (srcWidth > 0 && w > srcWidth) {
+			continue
+		}
+		result = append(result, w)
+	}
+	sort.Ints(result)
+	return result
+}
+
+// variantURL ссылка на вариант изображения: через пресет с теми же параметрами,
+// если он есть, иначе через /optimized-image. Ссылки через пресеты работают
+// и в режиме "только пресеты" (IMAGE_PRESETS_ONLY). Пресет того же размера
+// подходит и для другого формата: формат передается параметром fm. Маршрут
+// пресетов отдает только файлы из каталога медиа, поэтому удаленные исходники идут мимо него
+func variantURL(presets *image.Presets, path string, opts image.ProcessOptions, version string) string {
+	if !strings.HasPrefix(path, image.MediaURLPrefix) {
+		return image.BuildURL(path, opts, version)
+	}
+
+	// Качество 0 в ссылке означает качество по умолчанию, а в пресете оно всегда задано
+	match := opts
+	if match.Quality == 0 {
+		match.Quality = image.DefaultQuality
+	}
+	if name, ok := presets.Match(match); ok {
+		return image.PresetURL(name, path, image.FormatDefault, version)
+	}
+	if name, ok := presets.MatchSize(match); ok {
+		return image.PresetURL(name, path, opts.Format, version)
+	}
+	return image.BuildURL(path, opts, version)
+}
+
+// buildSrcSet формирует значение srcset вида "url 300w, url 600w"
+func buildSrcSet(presets *image.Presets, path, version string, widths []int, quality int, format image.Format) string {
+	parts := make([]string, 0, len(widths))
+	for _, w := range widths {
+		opts := image.ProcessOptions{Width: w, Quality: quality, Format: format}
+		parts = append(parts, variantURL(presets, path, opts, version)+" "+strconv.Itoa(w)+"w")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// loadingAttr значение атрибута loading
+func loadingAttr(eager bool) string {
+	if eager {
+		return "eager"
+	}
+	return "lazy"
+}
+
+// placeholderStyle стиль, который подкладывает LQIP-превью фоном под <img>
+func placeholderStyle(placeholder string) string {
+	return "background-image:url(" + placeholder + ");background-size:cover;background-repeat:no-repeat"
+}
+
+// Размеры превью для соцсетей (og:image): рекомендация Open Graph для широких карточек
+const (
+	OGImageWidth   = 1200
+	OGImageHeight  = 630
+	ogImageQuality = 85
+)
+
+// OGImageURL ссылка на превью исходника для соцсетей: 1200x630 с обрезкой по центру.
+// Параметры совпадают с пресетом og, поэтому ссылка ведет на /img/og/...: этот путь
+// открыт в robots.txt (в отличие от /optimized-image) и работает в режиме "только пресеты".
+// Соцсети не везде понимают WebP и AVIF, поэтому превью всегда JPEG
+func OGImageURL(ctx context.Context, path string) string {
+	images := imagesFrom(ctx)
+
+	var version string
+	if images.Service != nil {
+		if info, err := images.Service.SourceInfo(path); err == nil {
+			version = info.Version
+		}
+	}
+	opts := image.ProcessOptions{
+		Width:   OGImageWidth,
+		Height:  OGImageHeight,
+		Quality: ogImageQuality,
+		Format:  image.FormatJPEG,
+		Fit:     image.FitCover,
+	}
+	return variantURL(images.Presets, path, opts, version)
+}
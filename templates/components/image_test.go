@@ -0,0 +1,119 @@
+package components
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gin-starter/internal/service/image"
+	"gin-starter/internal/testutil"
+)
+
+// imagesContext контекст рендеринга с сервисом и пресетами
+func imagesContext(t *testing.T, service ImageService, presetSpec string) context.Context {
+	t.Helper()
+	presets, err := image.ParsePresets(presetSpec, nil)
+	if err != nil {
+		t.Fatalf("ParsePresets: %v", err)
+	}
+	return WithImages(context.Background(), Images{Service: service, Presets: presets})
+}
+
+func TestResponsiveImageUsesPresetURLs(t *testing.T) {
+	ctx := imagesContext(t, testutil.ImageService{Width: 800, Height: 400}, "sm=w:150,q:80; card=w:600,q:80")
+
+	img := newResponsiveImage(ctx, "/static/images/a.png", []int{600, 150, 300}, ImageOptions{Quality: 80})
+
+	want := "/img/sm/images/a.png?v=v1 150w, " +
+		"/optimized-image?path=%2Fstatic%2Fimages%2Fa.png&q=80&v=v1&w=300 300w, " +
+		"/img/card/images/a.png?v=v1 600w"
+	if img.SrcSet != want {
+		t.Fatalf("SrcSet = %q, want %q", img.SrcSet, want)
+	}
+	if img.Src != "/img/card/images/a.png?v=v1" || img.Width != 600 || img.Height != 300 {
+		t.Fatalf("Src = %q %dx%d, want card preset 600x300", img.Src, img.Width, img.Height)
+	}
+}
+
+func TestResponsiveImageDefaultQualityMatchesPreset(t *testing.T) {
+	ctx := imagesContext(t, testutil.ImageService{Width: 800, Height: 400}, "sm=w:150")
+
+	img := newResponsiveImage(ctx, "/static/images/a.png", []int{150}, ImageOptions{})
+	if img.Src != "/img/sm/images/a.png?v=v1" {
+		t.Fatalf("Src = %q, want sm preset for default quality", img.Src)
+	}
+}
+
+func TestResponsiveImageSmallSourceServesOriginal(t *testing.T) {
+	ctx := imagesContext(t, testutil.ImageService{Width: 100, Height: 50}, "sm=w:150")
+
+	img := newResponsiveImage(ctx, "/static/images/a.png", []int{150, 300}, ImageOptions{})
+	if img.Src != "/static/images/a.png" || img.SrcSet != "" || img.Width != 100 || img.Height != 50 {
+		t.Fatalf("image = %+v, want original file 100x50 without srcset", img)
+	}
+}
+
+func TestResponsiveImageRemoteSourceSkipsPresets(t *testing.T) {
+	ctx := imagesContext(t, testutil.ImageService{Width: 800, Height: 400}, "sm=w:150")
+
+	img := newResponsiveImage(ctx, "https://cdn.example.com/a.png", []int{150}, ImageOptions{})
+	if !strings.HasPrefix(img.Src, image.OptimizedImageRoute+"?") {
+		t.Fatalf("Src = %q, want /optimized-image link for remote source", img.Src)
+	}
+}
+
+func TestResponsiveImageOffersModernFormats(t *testing.T) {
+	ctx := imagesContext(t, testutil.ImageService{Width: 800, Height: 400}, "sm=w:150,q:80")
+
+	img := newResponsiveImage(ctx, "/static/images/a.png", []int{150, 300}, ImageOptions{Quality: 80})
+
+	want := []pictureSource{
+		{Type: "image/avif", SrcSet: "/img/sm/images/a.png?fm=avif&v=v1 150w, " +
+			"/optimized-image?fm=avif&path=%2Fstatic%2Fimages%2Fa.png&q=80&v=v1&w=300 300w"},
+		{Type: "image/webp", SrcSet: "/img/sm/images/a.png?fm=webp&v=v1 150w, " +
+			"/optimized-image?fm=webp&path=%2Fstatic%2Fimages%2Fa.png&q=80&v=v1&w=300 300w"},
+	}
+	if len(img.Sources) != len(want) {
+		t.Fatalf("Sources = %+v, want %+v", img.Sources, want)
+	}
+	for i := range want {
+		if img.Sources[i] != want[i] {
+			t.Errorf("Sources[%d] = %+v, want %+v", i, img.Sources[i], want[i])
+		}
+	}
+
+	var buf strings.Builder
+	if err := ResponsiveImage("/static/images/a.png", "A", "100vw", []int{150, 300}, ImageOptions{Quality: 80}).Render(ctx, &buf); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	html := buf.String()
+	avif := strings.Index(html, `<source type="image/avif"`)
+	webp := strings.Index(html, `<source type="image/webp"`)
+	fallback := strings.Index(html, `<img src="/optimized-image?path=`)
+	if !strings.HasPrefix(html, "<picture>") || avif < 0 || webp < avif || fallback < webp {
+		t.Fatalf("want <picture> with AVIF, then WebP sources, then <img>:\n%s", html)
+	}
+}
+
+func TestResponsiveImageSingleFormatWithoutPicture(t *testing.T) {
+	tests := map[string]struct {
+		service ImageService
+		opts    ImageOptions
+	}{
+		"explicit format": {testutil.ImageService{Width: 800, Height: 400}, ImageOptions{Format: image.FormatPNG}},
+		"animated source": {testutil.ImageService{Width: 800, Height: 400, Animated: true}, ImageOptions{}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := imagesContext(t, tt.service, "sm=w:150")
+
+			var buf strings.Builder
+			if err := ResponsiveImage("/static/images/a.gif", "A", "100vw", []int{150}, tt.opts).Render(ctx, &buf); err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			if html := buf.String(); !strings.HasPrefix(html, "<img ") || strings.Contains(html, "<source") {
+				t.Fatalf("want a single <img>:\n%s", html)
+			}
+		})
+	}
+}
@@ -1,17 +1,113 @@
 package header
 
-// MenuItem представляет элемент меню
+import (
+	"context"
+	"strings"
+
+	"gin-starter/internal/i18n"
+)
+
+// MenuItem пункт меню, готовый к выводу: текст на языке запроса, адрес
+// с языковым префиксом и признак текущей страницы
 type MenuItem struct {
-	URL  string
-	Text string
+	URL      string
+	Text     string
+	Icon     string // значок перед текстом (эмодзи или символ); пусто - без значка
+	Active   bool   // пункт ведет на текущую страницу или содержит ее среди вложенных
+	Children []MenuItem
+}
+
+// HasChildren сообщает, есть ли у пункта выпадающее подменю
+func (item MenuItem) HasChildren() bool {
+	return len(item.Children) > 0
+}
+
+// MenuEntry описание пункта меню в реестре
+type MenuEntry struct {
+	Path    string // путь без языкового префикса ("/about"); пусто - пункт-заголовок подменю
+	TextKey string // ключ текста в каталоге i18n
+	Icon    string
+	// Visible решает, показывать ли пункт в этом запросе; nil - всегда.
+	// Пункт с подменю, у которого не осталось видимых детей и нет своего адреса, скрывается
+	Visible  func(ctx context.Context) bool
+	Children []MenuEntry
+}
+
+// Menu реестр пунктов меню; настраивается один раз при старте (SetMenu)
+type Menu struct {
+	entries []MenuEntry
+}
+
+// NewMenu создает реестр меню из пунктов верхнего уровня
+func NewMenu(entries ...MenuEntry) *Menu {
+	return &Menu{entries: entries}
+}
+
+// Items пункты меню для запроса: тексты на языке из контекста, адреса с
+// языковым префиксом, Active по текущему пути (с префиксом или без)
+func (m *Menu) Items(ctx context.Context, currentPath string) []MenuItem {
+	if m == nil {
+		return nil
+	}
+	_, current := i18n.SplitPath(currentPath)
+	return buildItems(ctx, m.entries, current)
+}
+
+// buildItems переводит описания пунктов в пункты для вывода
+func buildItems(ctx context.Context, entries []MenuEntry, current string) []MenuItem {
+	items := make([]MenuItem, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Visible != nil && !entry.Visible(ctx) {
+			continue
+		}
+
+		item := MenuItem{
+			Text:     i18n.T(ctx, entry.TextKey),
+			Icon:     entry.Icon,
+			Children: buildItems(ctx, entry.Children, current),
+		}
+		if entry.Path != "" {
+			item.URL = i18n.Path(ctx, entry.Path)
+			item.Active = isActivePath(entry.Path, current)
+		} else if len(item.Children) == 0 {
+			continue
+		}
+		for _, child := range item.Children {
+			item.Active = item.Active || child.Active
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// isActivePath ведет ли пункт на текущую страницу: точное совпадение или
+// вложенный путь ("/users/5" для "/users"). Главная активна только сама по себе
+func isActivePath(entryPath, current string) bool {
+	if entryPath == current {
+		return true
+	}
+	return entryPath != "/" && strings.HasPrefix(current, entryPath+"/")
+}
+
+// siteMenu задается один раз при старте приложения (см. SetMenu); без него меню пустое
+var siteMenu *Menu
+
+// SetMenu подключает меню сайта к макету
+func SetMenu(menu *Menu) {
+	siteMenu = menu
+}
+
+// CurrentMenu возвращает меню сайта, которое выводит макет
+func CurrentMenu() *Menu {
+	return siteMenu
 }
 
-// GetDefaultMenuItems возвращает стандартный набор элементов меню
-func GetDefaultMenuItems() []MenuItem {
-	return []MenuItem{
-		{URL: "/", Text: "Главная"},
-		{URL: "/about", Text: "О проекте"},
-		{URL: "/contact", Text: "Контакты"},
-		{URL: "/users", Text: "Пользователи"},
+// childActive активен ли один из вложенных пунктов
+func childActive(item MenuItem) bool {
+	for _, child := range item.Children {
+		if child.Active {
+			return true
+		}
 	}
+	return false
 }
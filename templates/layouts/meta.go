@@ -0,0 +1,124 @@
+package layouts
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"gin-starter/internal/service/image"
+	"gin-starter/templates/components"
+	"gin-starter/templates/layouts/header"
+
+	"github.com/a-h/templ"
+)
+
+// SiteName название сайта для og:site_name и шаблона заголовка
+const SiteName = "Gin Starter"
+
+// DefaultTitleTemplate шаблон <title> по умолчанию; %s - заголовок страницы
+const DefaultTitleTemplate = "%s | " + SiteName
+
+// Alternate ссылка на версию страницы на другом языке (hreflang)
+type Alternate struct {
+	Lang string // код языка (ru, en) или x-default
+	URL  string // абсолютный адрес
+}
+
+// PageMeta метаданные страницы для <head>: SEO, Open Graph, Twitter и JSON-LD
+type PageMeta struct {
+	Title         string
+	TitleTemplate string // шаблон <title> с %s; пусто - DefaultTitleTemplate, "%s" - без суффикса
+	Description   string
+	Canonical     string // абсолютный канонический адрес
+	Path          string // путь запроса; по нему в меню подсвечивается текущая страница
+	// Image картинка превью: путь к исходнику (/static/images/a.png) - тогда
+	// превью 1200x630 готовит сервис изображений - или абсолютный адрес
+	Image      string
+	ImageAlt   string
+	Type       string      // og:type; пусто - website
+	Locale     string      // og:locale (ru_RU); пусто - ru_RU
+	Robots     []string    // директивы robots: noindex, nofollow и т.п.; пусто - тег не выводится
+	Alternates []Alternate // версии страницы на других языках
+	JSONLD     []any       // блоки структурированных данных schema.org
+}
+
+// FullTitle заголовок по шаблону
+func (m PageMeta) FullTitle() string {
+	if m.Title == "" {
+		return SiteName
+	}
+	tmpl := m.TitleTemplate
+	if tmpl == "" {
+		tmpl = DefaultTitleTemplate
+	}
+	return fmt.Sprintf(tmpl, m.Title)
+}
+
+// ogType тип страницы для Open Graph
+func (m PageMeta) ogType() string {
+	if m.Type == "" {
+		return "website"
+	}
+	return m.Type
+}
+
+// locale локаль для Open Graph
+func (m PageMeta) locale() string {
+	if m.Locale == "" {
+		return "ru_RU"
+	}
+	return m.Locale
+}
+
+// Lang язык для <html lang>: ru_RU -> ru
+func (m PageMeta) Lang() string {
+	lang, _, _ := strings.Cut(m.locale(), "_")
+	return strings.ToLower(lang)
+}
+
+// robotsContent значение <meta name="robots">
+func (m PageMeta) robotsContent() string {
+	return strings.Join(m.Robots, ", ")
+}
+
+// twitterCard большая карточка, если есть картинка
+func (m PageMeta) twitterCard() string {
+	if m.Image == "" {
+		return "summary"
+	}
+	return "summary_large_image"
+}
+
+// processedImage готовит ли превью сервис изображений (размеры известны заранее)
+func (m PageMeta) processedImage() bool {
+	return strings.HasPrefix(m.Image, image.MediaURLPrefix)
+}
+
+// imageURL абсолютный адрес превью. Соцсети не понимают относительные ссылки,
+// поэтому адрес строится от схемы и хоста канонического адреса
+func (m PageMeta) imageURL(ctx context.Context) string {
+	if m.Image == "" || strings.HasPrefix(m.Image, "https://") || strings.HasPrefix(m.Image, "http://") {
+		return m.Image
+	}
+
+	path := m.Image
+	if m.processedImage() {
+		path = components.OGImageURL(ctx, m.Image)
+	}
+	canonical, err := url.Parse(m.Canonical)
+	if err != nil || canonical.Host == "" {
+		return path
+	}
+	return canonical.Scheme + "://" + canonical.Host + path
+}
+
+// menuItems пункты меню сайта (header.SetMenu) для текущей страницы
+func (m PageMeta) menuItems(ctx context.Context) []header.MenuItem {
+	return header.CurrentMenu().Items(ctx, m.Path)
+}
+
+// jsonLD блок структурированных данных
+func jsonLD(data any) templ.Component {
+	return templ.JSONScript("", data).WithType("application/ld+json")
+}
@@ -0,0 +1,82 @@
+package layouts
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"gin-starter/internal/service/image"
+	"gin-starter/internal/testutil"
+	"gin-starter/templates/components"
+)
+
+// renderMetaTags подключает сервис изображений с пресетом og и рендерит теги <head>
+func renderMetaTags(t *testing.T, meta PageMeta) string {
+	t.Helper()
+	presets, err := image.ParsePresets("og=w:1200,h:630,fit:cover,q:85,fm:jpeg", nil)
+	if err != nil {
+		t.Fatalf("ParsePresets: %v", err)
+	}
+	ctx := components.WithImages(context.Background(), components.Images{Service: testutil.ImageService{Width: 1600, Height: 900}, Presets: presets})
+
+	var buf bytes.Buffer
+	if err := metaTags(meta).Render(ctx, &buf); err != nil {
+		t.Fatalf("render metaTags: %v", err)
+	}
+	return buf.String()
+}
+
+func TestMetaTagsRenderHeadTags(t *testing.T) {
+	html := renderMetaTags(t, PageMeta{
+		Title:       "About",
+		Description: "About us",
+		Canonical:   "https://example.com/about",
+		Image:       "/static/images/a.png",
+		ImageAlt:    "Logo",
+		Robots:      []string{"noindex", "nofollow"},
+		Alternates: []Alternate{
+			{Lang: "ru", URL: "https://example.com/about"},
+			{Lang: "en", URL: "https://example.com/en/about"},
+		},
+	})
+
+	for _, want := range []string{
+		`<link rel="canonical" href="https://example.com/about">`,
+		`<meta name="description" content="About us">`,
+		`<meta name="robots" content="noindex, nofollow">`,
+		`<link rel="alternate" hreflang="en" href="https://example.com/en/about">`,
+		`<meta property="og:title" content="About">`,
+		`<meta property="og:url" content="https://example.com/about">`,
+		`<meta name="twitter:card" content="summary_large_image">`,
+		// Превью идет через пресет og: /img/ открыт в robots.txt и работает с IMAGE_PRESETS_ONLY
+		`<meta property="og:image" content="https://example.com/img/og/images/a.png?v=v1">`,
+		`<meta name="twitter:image" content="https://example.com/img/og/images/a.png?v=v1">`,
+		`<meta property="og:image:width" content="1200">`,
+		`<meta property="og:image:height" content="630">`,
+		`<meta property="og:image:alt" content="Logo">`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("head tags do not contain %s\n%s", want, html)
+		}
+	}
+	if strings.Contains(html, image.OptimizedImageRoute) {
+		t.Errorf("og:image links to %s, which robots.txt disallows\n%s", image.OptimizedImageRoute, html)
+	}
+}
+
+func TestMetaTagsKeepAbsoluteImage(t *testing.T) {
+	html := renderMetaTags(t, PageMeta{
+		Title:     "Home",
+		Canonical: "https://example.com/",
+		Image:     "https://cdn.example.com/cover.jpg",
+	})
+
+	if !strings.Contains(html, `<meta property="og:image" content="https://cdn.example.com/cover.jpg">`) {
+		t.Errorf("absolute og:image was rewritten\n%s", html)
+	}
+	// Размеры неизвестны: превью не готовит сервис изображений
+	if strings.Contains(html, "og:image:width") {
+		t.Errorf("og:image:width rendered for an external image\n%s", html)
+	}
+}
@@ -0,0 +1,71 @@
+package pages
+
+import (
+	"strconv"
+
+	"gin-starter/internal/models"
+)
+
+// MaxContactNameLength максимальная длина имени в форме обратной связи (в символах)
+const MaxContactNameLength = 100
+
+// MaxContactMessageLength максимальная длина сообщения (в символах)
+const MaxContactMessageLength = 5000
+
+// ContactHoneypotField имя поля-ловушки: человек его не видит и оставляет
+// пустым, а боты заполняют все поля формы
+const ContactHoneypotField = "website"
+
+// ContactForm введенные значения, ошибки и результат формы обратной связи
+type ContactForm struct {
+	Name    string
+	Email   string
+	Message string
+	Errors  map[string]string // ошибки по полям: "name", "email", "message"
+	Error   string            // ошибка всей формы (лимит, сбой сохранения)
+	Sent    bool              // сообщение принято, показывается благодарность
+}
+
+// FieldError ошибка поля; пусто - ошибки нет
+func (f ContactForm) FieldError(field string) string {
+	return f.Errors[field]
+}
+
+// HasErrors есть ли в форме ошибки
+func (f ContactForm) HasErrors() bool {
+	return len(f.Errors) > 0 || f.Error != ""
+}
+
+// contactFieldErrorID id блока ошибки поля для aria-describedby
+func contactFieldErrorID(field string) string {
+	return "contact-" + field + "-error"
+}
+
+// ContactMessageList страница списка сообщений в админке
+type ContactMessageList struct {
+	Messages   []*models.ContactMessage
+	Page       int // с 1
+	TotalPages int
+	Total      int
+}
+
+// HasPrev есть ли предыдущая страница
+func (l ContactMessageList) HasPrev() bool {
+	return l.Page > 1
+}
+
+// HasNext есть ли следующая страница
+func (l ContactMessageList) HasNext() bool {
+	return l.Page < l.TotalPages
+}
+
+// PageURL адрес страницы списка; админка не переводится, поэтому без языкового префикса
+func (l ContactMessageList) PageURL(page int) string {
+	if page <= 1 {
+		return AdminContactMessagesPath
+	}
+	return AdminContactMessagesPath + "?page=" + strconv.Itoa(page)
+}
+
+// AdminContactMessagesPath адрес списка сообщений в админке
+const AdminContactMessagesPath = "/admin/contact-messages"
@@ -0,0 +1,41 @@
+package pages
+
+import (
+	"context"
+	"strconv"
+
+	"gin-starter/internal/i18n"
+)
+
+// ErrorView страница ошибки: код ответа, пояснение и id запроса для поддержки
+type ErrorView struct {
+	Status    int
+	Detail    string // пояснение обработчика; пусто - только общий текст
+	RequestID string
+}
+
+// errorPageKeys статусы, у которых есть свои тексты в каталоге ("error_page.<status>.*")
+var errorPageKeys = map[int]bool{400: true, 401: true, 403: true, 404: true, 429: true, 500: true, 503: true}
+
+// errorPageKey ключ текстов страницы: свой для известных статусов, иначе общий
+// для класса ошибки (400 или 500)
+func errorPageKey(status int) string {
+	switch {
+	case errorPageKeys[status]:
+	case status >= 500:
+		status = 500
+	default:
+		status = 400
+	}
+	return "error_page." + strconv.Itoa(status)
+}
+
+// ErrorTitle заголовок страницы ошибки на языке запроса
+func ErrorTitle(ctx context.Context, status int) string {
+	return i18n.T(ctx, errorPageKey(status)+".title")
+}
+
+// ErrorText общий текст страницы ошибки на языке запроса
+func ErrorText(ctx context.Context, status int) string {
+	return i18n.T(ctx, errorPageKey(status)+".text")
+}
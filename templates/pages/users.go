@@ -0,0 +1,108 @@
+package pages
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"gin-starter/internal/i18n"
+	"gin-starter/internal/models"
+
+	"github.com/a-h/templ"
+)
+
+// usersScriptMessages тексты для Alpine-компонента userData (static/js/app.js)
+// на языке запроса в виде JSON-объекта
+func usersScriptMessages(ctx context.Context) string {
+	messages := map[string]string{
+		"showForm":       i18n.T(ctx, "users.show_form"),
+		"hideForm":       i18n.T(ctx, "users.hide_form"),
+		"added":          i18n.T(ctx, "users.added"),
+		"confirmTitle":   i18n.T(ctx, "users.confirm_title"),
+		"confirmMessage": i18n.T(ctx, "users.confirm_message"),
+		"deleted":        i18n.T(ctx, "users.deleted"),
+		"requestFailed":  i18n.T(ctx, "users.request_failed"),
+	}
+	json, err := templ.JSONString(messages)
+	if err != nil {
+		return "{}"
+	}
+	return json
+}
+
+// MaxUserQueryLength максимальная длина строки поиска пользователей (в символах)
+const MaxUserQueryLength = 100
+
+// MaxUserNameLength максимальная длина имени пользователя (в символах)
+const MaxUserNameLength = 100
+
+// UserForm введенные значения и ошибка формы добавления пользователя;
+// нужна при повторном показе формы без JS
+type UserForm struct {
+	Name  string
+	Email string
+	Error string
+}
+
+// UserList страница списка пользователей: найденные пользователи, строка
+// поиска и положение в пагинации
+type UserList struct {
+	Users      []*models.User
+	Query      string
+	Page       int // с 1
+	TotalPages int
+	Total      int // всего найдено
+	Form       UserForm
+}
+
+// HasPrev есть ли предыдущая страница
+func (l UserList) HasPrev() bool {
+	return l.Page > 1
+}
+
+// HasNext есть ли следующая страница
+func (l UserList) HasNext() bool {
+	return l.Page < l.TotalPages
+}
+
+// PageURL адрес страницы списка с сохранением поиска; первая страница без параметра page
+func (l UserList) PageURL(ctx context.Context, page int) string {
+	query := url.Values{}
+	if l.Query != "" {
+		query.Set("q", l.Query)
+	}
+	if page > 1 {
+		query.Set("page", strconv.Itoa(page))
+	}
+
+	path := i18n.Path(ctx, "/users")
+	if encoded := query.Encode(); encoded != "" {
+		return path + "?" + encoded
+	}
+	return path
+}
+
+// PageNumbers номера страниц для ссылок: первая, последняя и соседние с
+// текущей; 0 - пропуск ("…")
+func (l UserList) PageNumbers() []int {
+	var numbers []int
+	for page := 1; page <= l.TotalPages; page++ {
+		if page == 1 || page == l.TotalPages || (page >= l.Page-2 && page <= l.Page+2) {
+			numbers = append(numbers, page)
+		} else if len(numbers) > 0 && numbers[len(numbers)-1] != 0 {
+			numbers = append(numbers, 0)
+		}
+	}
+	return numbers
+}
+
+// userDeletePath адрес формы удаления пользователя
+func userDeletePath(ctx context.Context, id uint) string {
+	return i18n.Path(ctx, "/users/"+strconv.FormatUint(uint64(id), 10)+"/delete")
+}
+
+// formatUserDate дата создания пользователя в формате языка страницы
+func formatUserDate(ctx context.Context, t time.Time) string {
+	return t.Format(i18n.T(ctx, "users.date_format"))
+}
@@ -1,6 +1,9 @@
 package templates
 
 import (
+	"context"
+
+	"gin-starter/internal/models"
 	layouts "gin-starter/templates/layouts"
 	footer "gin-starter/templates/layouts/footer"
 	header "gin-starter/templates/layouts/header"
@@ -9,39 +12,106 @@ import (
 	"github.com/a-h/templ"
 )
 
-// GetDefaultMenuItems возвращает стандартный набор элементов меню
-func GetDefaultMenuItems() []header.MenuItem {
-	return header.GetDefaultMenuItems()
+// PageMeta метаданные страницы для <head> (см. layouts.PageMeta)
+type PageMeta = layouts.PageMeta
+
+// Alternate ссылка на версию страницы на другом языке
+type Alternate = layouts.Alternate
+
+// UserList страница списка пользователей (см. pages.UserList)
+type UserList = pages.UserList
+
+// UserForm значения и ошибка формы добавления пользователя
+type UserForm = pages.UserForm
+
+// MaxUserQueryLength максимальная длина строки поиска пользователей
+const MaxUserQueryLength = pages.MaxUserQueryLength
+
+// MaxUserNameLength максимальная длина имени пользователя
+const MaxUserNameLength = pages.MaxUserNameLength
+
+// ErrorView код, пояснение и id запроса для страницы ошибки
+type ErrorView = pages.ErrorView
+
+// ContactForm значения, ошибки и результат формы обратной связи (см. pages.ContactForm)
+type ContactForm = pages.ContactForm
+
+// ContactMessageList страница списка сообщений в админке
+type ContactMessageList = pages.ContactMessageList
+
+// MaxContactNameLength максимальная длина имени в форме обратной связи
+const MaxContactNameLength = pages.MaxContactNameLength
+
+// MaxContactMessageLength максимальная длина сообщения формы обратной связи
+const MaxContactMessageLength = pages.MaxContactMessageLength
+
+// ContactHoneypotField имя поля-ловушки для ботов в форме обратной связи
+const ContactHoneypotField = pages.ContactHoneypotField
+
+// ErrorTitle заголовок страницы ошибки для статуса на языке запроса
+func ErrorTitle(ctx context.Context, status int) string {
+	return pages.ErrorTitle(ctx, status)
+}
+
+// ErrorText общий текст страницы ошибки для статуса на языке запроса
+func ErrorText(ctx context.Context, status int) string {
+	return pages.ErrorText(ctx, status)
+}
+
+// SetMenu подключает меню сайта к макету (см. header.SetMenu)
+func SetMenu(menu *header.Menu) {
+	header.SetMenu(menu)
 }
 
 // Обертки для шаблонов страниц
-func IndexPage(canonicalURL string, menuItems []header.MenuItem) templ.Component {
-	return pages.IndexPage(canonicalURL, menuItems)
+func IndexPage(meta PageMeta) templ.Component {
+	return pages.IndexPage(meta)
+}
+
+func AboutPage(meta PageMeta) templ.Component {
+	return pages.AboutPage(meta)
+}
+
+func ContactPage(meta PageMeta, form ContactForm) templ.Component {
+	return pages.ContactPage(meta, form)
+}
+
+func AdminContactMessagesPage(meta PageMeta, list ContactMessageList) templ.Component {
+	return pages.AdminContactMessagesPage(meta, list)
+}
+
+func UsersPage(meta PageMeta, list UserList) templ.Component {
+	return pages.UsersPage(meta, list)
+}
+
+// Фрагменты страницы пользователей для ответов htmx
+func UsersList(list UserList) templ.Component {
+	return pages.UsersList(list)
 }
 
-func AboutPage(canonicalURL string, menuItems []header.MenuItem) templ.Component {
-	return pages.AboutPage(canonicalURL, menuItems)
+func UserRow(user *models.User) templ.Component {
+	return pages.UserRow(user)
 }
 
-func ContactPage(canonicalURL string, menuItems []header.MenuItem) templ.Component {
-	return pages.ContactPage(canonicalURL, menuItems)
+func UsersSummary(total int, query string, oob bool) templ.Component {
+	return pages.UsersSummary(total, query, oob)
 }
 
-func UsersPage(canonicalURL string, menuItems []header.MenuItem) templ.Component {
-	return pages.UsersPage(canonicalURL, menuItems)
+func UserFormError(message string) templ.Component {
+	return pages.UserFormError(message)
 }
 
-func NotFoundPage(canonicalURL string, menuItems []header.MenuItem) templ.Component {
-	return pages.NotFoundPage(canonicalURL, menuItems)
+func ErrorPage(meta PageMeta, view ErrorView) templ.Component {
+	return pages.ErrorPage(meta, view)
 }
 
 // Обертки для шаблонов макетов
-func Layout(title string, description string, canonicalURL string, menuItems []header.MenuItem, body templ.Component) templ.Component {
-	return layouts.Layout(title, description, canonicalURL, menuItems, body)
+func Layout(meta PageMeta, body templ.Component) templ.Component {
+	return layouts.Layout(meta, body)
 }
 
-func Header(menuItems []header.MenuItem) templ.Component {
-	return header.Header(menuItems)
+func Header(menuItems []header.MenuItem, languages templ.Component) templ.Component {
+	return header.Header(menuItems, languages)
 }
 
 func Footer(menuItems []header.MenuItem, currentYear int) templ.Component {